@@ -3,6 +3,7 @@ package migrate
 import (
 	"context"
 	"testing"
+	"testing/fstest"
 
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
@@ -53,6 +54,9 @@ func TestMigrationsRegistration(t *testing.T) {
 	if registered[0].Version != 1 || registered[0].Description != "global_migrate_test" {
 		t.Errorf("Unexpected version/description: %d %s", registered[0].Version, registered[0].Description)
 	}
+	if registered[0].Checksum == "" {
+		t.Errorf("Unexpected empty checksum")
+	}
 
 	err = Register(func(ctx context.Context, db *mongo.Database) error {
 		return nil
@@ -87,3 +91,52 @@ func TestMigrationMustRegistration(t *testing.T) {
 		t.Errorf("Unexpected version/description: %d %s", registered[0].Version, registered[0].Description)
 	}
 }
+
+func TestMigrationsRegisterVersion(t *testing.T) {
+	oldMigrate := globalMigrate
+	defer func() {
+		globalMigrate = oldMigrate
+	}()
+	globalMigrate = NewMigrate(nil)
+
+	err := RegisterVersion(10, "explicit", func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	}, nil)
+	if err != nil {
+		t.Errorf("Unexpected register error: %v", err)
+		return
+	}
+	registered := RegisteredMigrations()
+	if len(registered) != 1 || registered[0].Version != 10 || registered[0].Description != "explicit" {
+		t.Errorf("Unexpected registered migrations: %+v", registered)
+		return
+	}
+	if err := RegisterVersion(10, "again", nil, nil); err == nil {
+		t.Errorf("Unexpected nil error")
+	}
+}
+
+func TestMigrationsRegisterFS(t *testing.T) {
+	oldMigrate := globalMigrate
+	defer func() {
+		globalMigrate = oldMigrate
+	}()
+	globalMigrate = NewMigrate(nil)
+
+	fsys := fstest.MapFS{
+		"migrations/1_ping.up.json":  {Data: []byte(`{"ping": 1}`)},
+		"migrations/2_hello.up.json": {Data: []byte(`{"hello": 1}`)},
+	}
+	if err := RegisterFS(fsys, "migrations"); err != nil {
+		t.Errorf("Unexpected register error: %v", err)
+		return
+	}
+	registered := RegisteredMigrations()
+	if len(registered) != 2 || registered[0].Version != 1 || registered[1].Description != "hello" {
+		t.Errorf("Unexpected registered migrations: %+v", registered)
+		return
+	}
+	if err := RegisterFS(fsys, "migrations"); err == nil {
+		t.Errorf("Unexpected nil error")
+	}
+}
@@ -0,0 +1,230 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	defaultCheckpointsCollectionSuffix = "_checkpoints"
+	defaultBackfillBatchSize           = 1000
+)
+
+// BackfillOptions configures Backfill.
+type BackfillOptions struct {
+	// Name identifies checkpoint of backfill, it must be unique for backfills of one migration.
+	// By default, it is a name of processed collection.
+	Name string
+	// Filter selects documents to process. By default, all documents are processed.
+	Filter bson.D
+	// BatchSize is a number of documents read and written at once. Default is 1000.
+	BatchSize int
+	// CheckpointCollection is a name of collection storing checkpoints in database of processed collection.
+	// By default, it is a name of migrations collection with "_checkpoints" suffix.
+	CheckpointCollection string
+	// Throttle limits speed of processing.
+	Throttle ThrottleOptions
+}
+
+// BackfillResult describes documents processed by Backfill including ones processed before resuming.
+type BackfillResult struct {
+	// Processed is a number of read documents.
+	Processed int64
+	// Written is a number of write models returned by transform.
+	Written int64
+}
+
+type checkpointRecord struct {
+	ID        string    `bson:"_id"`
+	LastID    any       `bson:"lastId"`
+	Processed int64     `bson:"processed"`
+	Written   int64     `bson:"written"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+// Backfill walks collection documents in "_id" order by batches calling transform for each of them
+// and writes returned models using BulkWrite. Transform may return nil model to skip document.
+// After each batch checkpoint with last processed "_id" and counters is saved, so if migration fails or process crashes,
+// re-run of migration resumes from the checkpoint. Checkpoint is removed when all documents are processed.
+// Batch may be processed twice if process crashed between write and checkpoint saving, so transform should be idempotent.
+//
+// Example:
+//
+//	func(ctx context.Context, db *mongo.Database) error {
+//		_, err := migrate.Backfill(ctx, db.Collection("users"), func(ctx context.Context, user User) (mongo.WriteModel, error) {
+//			return mongo.NewUpdateOneModel().
+//				SetFilter(bson.D{{Key: "_id", Value: user.ID}}).
+//				SetUpdate(bson.D{{Key: "$set", Value: bson.D{{Key: "email", Value: strings.ToLower(user.Email)}}}}), nil
+//		}, migrate.BackfillOptions{})
+//		return err
+//	}
+func Backfill[T any](ctx context.Context, coll *mongo.Collection, transform func(ctx context.Context, doc T) (mongo.WriteModel, error), opts BackfillOptions) (BackfillResult, error) {
+	b := newBackfill(ctx, coll, opts)
+	throttler := NewThrottler(coll.Database().Client(), opts.Throttle)
+
+	checkpoint, err := b.loadCheckpoint(ctx)
+	if err != nil {
+		return BackfillResult{}, err
+	}
+
+	for {
+		docs, err := b.nextBatch(ctx, checkpoint.LastID)
+		if err != nil {
+			return checkpoint.result(), err
+		}
+		if len(docs) == 0 {
+			break
+		}
+
+		models := make([]mongo.WriteModel, 0, len(docs))
+		for _, raw := range docs {
+			var doc T
+			if err := bson.Unmarshal(raw, &doc); err != nil {
+				return checkpoint.result(), fmt.Errorf("migrate: decode document %s: %w", raw.Lookup("_id"), err)
+			}
+			model, err := transform(ctx, doc)
+			if err != nil {
+				return checkpoint.result(), fmt.Errorf("migrate: transform document %s: %w", raw.Lookup("_id"), err)
+			}
+			if model != nil {
+				models = append(models, model)
+			}
+		}
+
+		if len(models) > 0 {
+			if _, err := coll.BulkWrite(ctx, models); err != nil {
+				return checkpoint.result(), err
+			}
+		}
+
+		var lastID any
+		if err := docs[len(docs)-1].Lookup("_id").Unmarshal(&lastID); err != nil {
+			return checkpoint.result(), err
+		}
+		checkpoint.LastID = lastID
+		checkpoint.Processed += int64(len(docs))
+		checkpoint.Written += int64(len(models))
+		if err := b.saveCheckpoint(ctx, checkpoint); err != nil {
+			return checkpoint.result(), err
+		}
+		if len(docs) < b.batchSize {
+			break
+		}
+		if err := throttler.Wait(ctx, len(docs)); err != nil {
+			return checkpoint.result(), err
+		}
+	}
+
+	return checkpoint.result(), b.removeCheckpoint(ctx)
+}
+
+type backfill struct {
+	coll        *mongo.Collection
+	checkpoints *mongo.Collection
+	id          string
+	filter      bson.D
+	batchSize   int
+}
+
+func newBackfill(ctx context.Context, coll *mongo.Collection, opts BackfillOptions) *backfill {
+	id, checkpoints := checkpointLocation(ctx, coll.Name(), opts)
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBackfillBatchSize
+	}
+
+	return &backfill{
+		coll:        coll,
+		checkpoints: coll.Database().Collection(checkpoints),
+		id:          id,
+		filter:      opts.Filter,
+		batchSize:   batchSize,
+	}
+}
+
+// checkpointLocation returns checkpoint id and name of checkpoints collection for backfill of collection.
+func checkpointLocation(ctx context.Context, collection string, opts BackfillOptions) (string, string) {
+	mc, _ := ctx.Value(migrationContextKey{}).(migrationContext)
+
+	name := opts.Name
+	if name == "" {
+		name = collection
+	}
+	// checkpoint of backfill called outside of migration is identified by name only
+	id := name
+	if mc.version != 0 {
+		id = fmt.Sprintf("%d_%s_%s", mc.version, mc.direction, name)
+	}
+
+	checkpoints := opts.CheckpointCollection
+	switch {
+	case checkpoints != "":
+	case mc.checkpoints != "":
+		checkpoints = mc.checkpoints
+	default:
+		checkpoints = defaultMigrationsCollection + defaultCheckpointsCollectionSuffix
+	}
+
+	return id, checkpoints
+}
+
+func (c checkpointRecord) result() BackfillResult {
+	return BackfillResult{Processed: c.Processed, Written: c.Written}
+}
+
+func (b *backfill) loadCheckpoint(ctx context.Context) (checkpointRecord, error) {
+	var checkpoint checkpointRecord
+	err := b.checkpoints.FindOne(ctx, bson.D{{Key: "_id", Value: b.id}}).Decode(&checkpoint)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return checkpointRecord{ID: b.id}, nil
+	case err != nil:
+		return checkpointRecord{}, fmt.Errorf("migrate: load backfill checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+func (b *backfill) saveCheckpoint(ctx context.Context, checkpoint checkpointRecord) error {
+	checkpoint.UpdatedAt = time.Now().UTC()
+	opts := options.Replace().SetUpsert(true)
+	if _, err := b.checkpoints.ReplaceOne(ctx, bson.D{{Key: "_id", Value: b.id}}, checkpoint, opts); err != nil {
+		return fmt.Errorf("migrate: save backfill checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (b *backfill) removeCheckpoint(ctx context.Context) error {
+	if _, err := b.checkpoints.DeleteOne(ctx, bson.D{{Key: "_id", Value: b.id}}); err != nil {
+		return fmt.Errorf("migrate: remove backfill checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (b *backfill) nextBatch(ctx context.Context, lastID any) ([]bson.Raw, error) {
+	filter := bson.D{}
+	if len(b.filter) > 0 {
+		filter = append(filter, bson.E{Key: "$and", Value: bson.A{b.filter}})
+	}
+	if lastID != nil {
+		filter = append(filter, bson.E{Key: "_id", Value: bson.D{{Key: "$gt", Value: lastID}}})
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(b.batchSize))
+	cursor, err := b.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []bson.Raw
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
@@ -0,0 +1,26 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckpointLocation(t *testing.T) {
+	id, checkpoints := checkpointLocation(context.Background(), "users", BackfillOptions{})
+	if id != "users" || checkpoints != "migrations_checkpoints" {
+		t.Errorf("Unexpected checkpoint location: %v %v", id, checkpoints)
+	}
+
+	migrate := NewMigrate(nil)
+	migrate.SetMigrationsCollection("history")
+	ctx := migrate.withMigrationContext(context.Background(), Migration{Version: 3}, DirectionUp, nil)
+	id, checkpoints = checkpointLocation(ctx, "users", BackfillOptions{Name: "emails"})
+	if id != "3_up_emails" || checkpoints != "history_checkpoints" {
+		t.Errorf("Unexpected checkpoint location: %v %v", id, checkpoints)
+	}
+
+	id, checkpoints = checkpointLocation(ctx, "users", BackfillOptions{CheckpointCollection: "progress"})
+	if id != "3_up_users" || checkpoints != "progress" {
+		t.Errorf("Unexpected checkpoint location: %v %v", id, checkpoints)
+	}
+}
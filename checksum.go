@@ -0,0 +1,69 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ChecksumMismatchError returned by "Up" and "To" when checksums validation is enabled
+// and checksums of registered migrations differ from ones stored on applying.
+type ChecksumMismatchError struct {
+	// Versions contains versions of migrations which were changed after applying.
+	Versions []uint64
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	versions := make([]string, len(e.Versions))
+	for i, v := range e.Versions {
+		versions[i] = fmt.Sprint(v)
+	}
+	return fmt.Sprintf("migrate: applied migrations were changed: %s", strings.Join(versions, ", "))
+}
+
+// fileChecksum returns hex-encoded SHA-256 of file content.
+// Empty string returned if file can not be read (e.g. binary is running without sources).
+func fileChecksum(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// SetValidateChecksums enables validation of applied migrations checksums in "Up" and in "To" if it applies migrations.
+// Migration considered changed if both its current checksum and checksum stored on applying are known and differ.
+func (m *Migrate) SetValidateChecksums(validate bool) {
+	m.validateChecksums = validate
+}
+
+// checksumMismatches returns versions of applied registered migrations with changed checksums.
+func (m *Migrate) checksumMismatches(state versionState) []uint64 {
+	migrationSort(m.migrations)
+
+	var ret []uint64
+	for _, migration := range m.migrations {
+		applied, ok := state.checksums[migration.Version]
+		if !ok || applied == "" || migration.Checksum == "" || !state.isApplied(migration.Version) {
+			continue
+		}
+		if applied != migration.Checksum {
+			ret = append(ret, migration.Version)
+		}
+	}
+	return ret
+}
+
+func (m *Migrate) checkChecksums(state versionState) error {
+	if !m.validateChecksums {
+		return nil
+	}
+	if versions := m.checksumMismatches(state); len(versions) > 0 {
+		return &ChecksumMismatchError{Versions: versions}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestChecksumMismatches(t *testing.T) {
+	migrate := NewMigrate(nil,
+		Migration{Version: 1, Checksum: "a"},
+		Migration{Version: 2, Checksum: "changed"},
+		Migration{Version: 3},
+		Migration{Version: 4, Checksum: "d"},
+	)
+	state := stateFromRecord(VersionRecord{
+		Version:   3,
+		Applied:   []uint64{1, 2, 3},
+		Checksums: map[string]string{"1": "a", "2": "b", "3": "c"},
+	})
+
+	if versions := migrate.checksumMismatches(state); !reflect.DeepEqual(versions, []uint64{2}) {
+		t.Errorf("Unexpected mismatches: %v", versions)
+	}
+	if err := migrate.checkChecksums(state); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	migrate.SetValidateChecksums(true)
+	var checksumErr *ChecksumMismatchError
+	if err := migrate.checkChecksums(state); !errors.As(err, &checksumErr) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestFileChecksum(t *testing.T) {
+	if checksum := fileChecksum("checksum_test.go"); len(checksum) != 64 {
+		t.Errorf("Unexpected checksum: %q", checksum)
+	}
+	if checksum := fileChecksum("not_exists.go"); checksum != "" {
+		t.Errorf("Unexpected checksum: %q", checksum)
+	}
+}
+
+func TestPlanToChecksums(t *testing.T) {
+	noop := func(ctx context.Context, db *mongo.Database) error { return nil }
+	migrate := NewMigrate(nil,
+		Migration{Version: 1, Checksum: "changed", Up: noop},
+		Migration{Version: 2, Checksum: "b", Up: noop},
+		Migration{Version: 3, Checksum: "changed", Up: noop, Down: noop},
+	)
+	migrate.SetValidateChecksums(true)
+	migrate.SetOutOfOrderPolicy(OutOfOrderAllow)
+	state := stateFromRecord(VersionRecord{
+		Version:   3,
+		Applied:   []uint64{1, 3},
+		Checksums: map[string]string{"1": "a", "3": "c"},
+	})
+
+	var checksumErr *ChecksumMismatchError
+	if _, err := migrate.planTo(state, 2); !errors.As(err, &checksumErr) || !reflect.DeepEqual(checksumErr.Versions, []uint64{1}) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// only reverting, changed migrations are not checked
+	state = stateFromRecord(VersionRecord{
+		Version:   3,
+		Applied:   []uint64{1, 2, 3},
+		Checksums: map[string]string{"1": "a", "2": "b", "3": "c"},
+	})
+	if _, err := migrate.planTo(state, 2); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,327 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Environment variables used by command line interface as defaults for flags.
+const (
+	EnvURI        = "MONGO_MIGRATE_URI"
+	EnvDatabase   = "MONGO_MIGRATE_DATABASE"
+	EnvCollection = "MONGO_MIGRATE_COLLECTION"
+	EnvOperator   = "MONGO_MIGRATE_OPERATOR"
+)
+
+const cliUsage = `Usage: %s [flags] <command> [arguments]
+
+Commands:
+  up [n]                    apply all or n pending migrations
+  down [n]                  revert all or n applied migrations
+  to <version>              migrate up or down to provided version
+  status                    show status of migrations
+  history [n]               show all or n latest history entries
+  version                   show current database version
+  force <version> [desc]    forcibly set database version
+  repair                    clear dirty state after failed migration
+  create [-dir <dir>] [-timestamp] <description>
+                            create new migration file
+
+Flags:
+`
+
+// errUsage returned when command line arguments are invalid.
+var errUsage = errors.New("migrate: invalid usage")
+
+type cliCommand struct {
+	uri        string
+	database   string
+	collection string
+	operator   string
+	name       string
+	args       []string
+}
+
+// Main runs command line interface which performs migrations from your own binary and exits.
+// If no migrations provided registered ones are used with global settings (logger, lock options, etc.).
+// Connection parameters are taken from flags or environment variables (see EnvURI, EnvDatabase and EnvCollection).
+//
+// Example:
+//
+//	package main
+//
+//	import (
+//		migrate "github.com/xakep666/mongo-migrate"
+//		_ "path/to/migrations_package" // database migrations
+//	)
+//
+//	func main() {
+//		migrate.Main()
+//	}
+func Main(migrations ...Migration) {
+	// interrupted migrations are stopped gracefully, so lock is released and failure is recorded
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := runCLI(ctx, os.Args, os.Stdout, os.Stderr, migrations)
+	stop()
+
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(0)
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func parseCLI(args []string, output io.Writer) (cliCommand, error) {
+	var cmd cliCommand
+
+	name := "mongo-migrate"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(output, cliUsage, name)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cmd.uri, "uri", os.Getenv(EnvURI), "MongoDB connection URI (env "+EnvURI+")")
+	fs.StringVar(&cmd.database, "database", os.Getenv(EnvDatabase), "database name, by default taken from URI path (env "+EnvDatabase+")")
+	fs.StringVar(&cmd.collection, "collection", os.Getenv(EnvCollection), "migrations collection name (env "+EnvCollection+")")
+	fs.StringVar(&cmd.operator, "operator", os.Getenv(EnvOperator), "operator name stored in migrations history (env "+EnvOperator+")")
+	if err := fs.Parse(args); err != nil {
+		return cmd, err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return cmd, fmt.Errorf("%w: command required", errUsage)
+	}
+	cmd.name, cmd.args = fs.Arg(0), fs.Args()[1:]
+
+	// creating migration files doesn't require connection
+	if cmd.name == "create" {
+		return cmd, nil
+	}
+
+	if cmd.uri == "" {
+		return cmd, fmt.Errorf("%w: connection URI required", errUsage)
+	}
+	if cmd.database == "" {
+		u, err := url.Parse(cmd.uri)
+		if err != nil {
+			return cmd, err
+		}
+		cmd.database = strings.TrimPrefix(u.Path, "/")
+	}
+	if cmd.database == "" {
+		return cmd, fmt.Errorf("%w: database name required", errUsage)
+	}
+
+	return cmd, nil
+}
+
+func runCLI(ctx context.Context, args []string, stdout, stderr io.Writer, migrations []Migration) error {
+	cmd, err := parseCLI(args, stderr)
+	if err != nil {
+		return err
+	}
+	if cmd.name == "create" {
+		return runCreate(cmd.args, stdout, stderr)
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(cmd.uri))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(ctx)
+
+	m := globalMigrate
+	if len(migrations) > 0 {
+		m = NewMigrate(nil, migrations...)
+	}
+	m.db = client.Database(cmd.database)
+	if cmd.collection != "" {
+		m.SetMigrationsCollection(cmd.collection)
+	}
+	if cmd.operator != "" {
+		m.SetOperator(cmd.operator)
+	}
+	if m.log == nil {
+		m.SetLogger(log.New(stdout, "", log.LstdFlags))
+	}
+
+	return m.runCommand(ctx, cmd.name, cmd.args, stdout)
+}
+
+func (m *Migrate) runCommand(ctx context.Context, name string, args []string, stdout io.Writer) error {
+	switch name {
+	case "up", "down":
+		n := AllAvailable
+		if len(args) > 0 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil {
+				return fmt.Errorf("%w: invalid number of migrations %q", errUsage, args[0])
+			}
+		}
+		if name == "up" {
+			return m.Up(ctx, n)
+		}
+		return m.Down(ctx, n)
+	case "to":
+		if len(args) != 1 {
+			return fmt.Errorf("%w: target version required", errUsage)
+		}
+		target, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid version %q", errUsage, args[0])
+		}
+		return m.To(ctx, target)
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printStatus(stdout, status)
+	case "history":
+		var filter HistoryFilter
+		if len(args) > 0 {
+			var err error
+			if filter.Limit, err = strconv.Atoi(args[0]); err != nil {
+				return fmt.Errorf("%w: invalid number of entries %q", errUsage, args[0])
+			}
+		}
+		entries, err := m.History(ctx, filter)
+		if err != nil {
+			return err
+		}
+		return printHistory(stdout, entries)
+	case "version":
+		version, description, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(stdout, version, description)
+		return err
+	case "force":
+		if len(args) == 0 {
+			return fmt.Errorf("%w: version required", errUsage)
+		}
+		version, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid version %q", errUsage, args[0])
+		}
+		description := strings.Join(args[1:], " ")
+		if description == "" {
+			for _, migration := range m.migrations {
+				if migration.Version == version {
+					description = migration.Description
+				}
+			}
+		}
+		return m.SetVersion(ctx, version, description)
+	case "repair":
+		return m.Repair(ctx)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, name)
+	}
+}
+
+func runCreate(args []string, stdout, stderr io.Writer) error {
+	var opts CreateOptions
+
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dir := fs.String("dir", ".", "directory with migration files")
+	fs.BoolVar(&opts.Timestamp, "timestamp", false, "use current time as version instead of next sequential one")
+	fs.StringVar(&opts.Package, "package", "", "package name, by default taken from existing files or directory name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("%w: migration description required", errUsage)
+	}
+
+	path, err := Create(*dir, strings.Join(fs.Args(), " "), opts)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, path)
+	return err
+}
+
+func printStatus(w io.Writer, status *StatusReport) error {
+	if _, err := fmt.Fprintf(w, "Current version: %d %s\n", status.Version, status.Description); err != nil {
+		return err
+	}
+	if status.Dirty != nil {
+		if _, err := fmt.Fprintf(w, "Dirty: %s migration %d %s started at %s was not completed\n", status.Dirty.Direction,
+			status.Dirty.Version, status.Dirty.Description, status.Dirty.StartedAt.Format("2006-01-02 15:04:05")); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tDESCRIPTION\tSTATE\tAPPLIED AT")
+	row := func(ms MigrationStatus, state string) {
+		appliedAt := ""
+		if !ms.AppliedAt.IsZero() {
+			appliedAt = ms.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", ms.Version, ms.Description, state, appliedAt)
+	}
+	for _, ms := range status.Migrations {
+		switch {
+		case ms.Changed:
+			row(ms, "changed")
+		case ms.Applied:
+			row(ms, "applied")
+		default:
+			row(ms, "pending")
+		}
+	}
+	for _, ms := range status.Unknown {
+		row(ms, "unknown")
+	}
+	return tw.Flush()
+}
+
+func printHistory(w io.Writer, entries []HistoryEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tEVENT\tMIGRATION\tVERSION\tDURATION\tHOST\tAPPLICATION\tOPERATOR\tERROR")
+	for _, e := range entries {
+		event := string(e.Event)
+		if e.Direction != "" {
+			event += " " + string(e.Direction)
+		}
+		migration := ""
+		if e.MigrationVersion != 0 {
+			migration = strings.TrimSpace(fmt.Sprintf("%d %s", e.MigrationVersion, e.MigrationDescription))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", e.Timestamp.Format("2006-01-02 15:04:05"), event,
+			migration, e.Version, e.Duration, e.Host, e.Application, e.Operator, e.Error)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,50 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestParseCLI(t *testing.T) {
+	t.Setenv(EnvURI, "")
+	t.Setenv(EnvDatabase, "")
+	t.Setenv(EnvCollection, "")
+	t.Setenv(EnvOperator, "")
+
+	cmd, err := parseCLI([]string{"mongo-migrate", "-uri", "mongodb://localhost:27017/test", "up", "2"}, io.Discard)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if cmd.database != "test" || cmd.name != "up" || len(cmd.args) != 1 || cmd.args[0] != "2" {
+		t.Errorf("Unexpected command: %+v", cmd)
+	}
+
+	t.Setenv(EnvURI, "mongodb://localhost:27017")
+	t.Setenv(EnvDatabase, "db")
+	t.Setenv(EnvCollection, "history")
+	t.Setenv(EnvOperator, "ci")
+	cmd, err = parseCLI([]string{"mongo-migrate", "status"}, io.Discard)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if cmd.database != "db" || cmd.collection != "history" || cmd.operator != "ci" || cmd.name != "status" {
+		t.Errorf("Unexpected command: %+v", cmd)
+	}
+
+	if _, err := parseCLI([]string{"mongo-migrate"}, io.Discard); !errors.Is(err, errUsage) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestRunCommandUsage(t *testing.T) {
+	migrate := NewMigrate(nil)
+	for _, args := range [][]string{{"unknown"}, {"up", "x"}, {"to"}, {"force", "x"}, {"history", "x"}} {
+		if err := migrate.runCommand(context.Background(), args[0], args[1:], io.Discard); !errors.Is(err, errUsage) {
+			t.Errorf("Unexpected error for %v: %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+// Command mongo-migrate performs migrations registered in your MongoDB.
+//
+// This binary has no migrations registered, so it is useful only to inspect and force database version.
+// To perform migrations build your own binary which imports package with migrations:
+//
+//	package main
+//
+//	import (
+//		migrate "github.com/xakep666/mongo-migrate"
+//		_ "path/to/migrations_package" // database migrations
+//	)
+//
+//	func main() {
+//		migrate.Main()
+//	}
+//
+// Run "mongo-migrate -h" to see available commands and flags.
+package main
+
+import migrate "github.com/xakep666/mongo-migrate"
+
+func main() {
+	migrate.Main()
+}
@@ -0,0 +1,241 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const lockID = "migrate"
+
+// CollectionStore is a VersionStore keeping history in collection of MongoDB database.
+// Each record is a separate document, the latest one has the biggest "_id".
+// Lock is a single document in dedicated collection which stores owner id and lease expiration time.
+type CollectionStore struct {
+	db             *mongo.Database
+	collection     string
+	lockCollection string
+}
+
+// NewCollectionStore creates store keeping history in collection of provided database
+// and lock in lockCollection of the same database.
+// Empty names mean "migrations" and name of history collection with "_lock" suffix respectively.
+func NewCollectionStore(db *mongo.Database, collection, lockCollection string) *CollectionStore {
+	if collection == "" {
+		collection = defaultMigrationsCollection
+	}
+	if lockCollection == "" {
+		lockCollection = collection + defaultLockCollectionSuffix
+	}
+	return &CollectionStore{db: db, collection: collection, lockCollection: lockCollection}
+}
+
+type collectionSpecification struct {
+	Name string `bson:"name"`
+	Type string `bson:"type"`
+}
+
+type lockRecord struct {
+	ID         string    `bson:"_id"`
+	Owner      string    `bson:"owner"`
+	AcquiredAt time.Time `bson:"acquiredAt"`
+	ExpiresAt  time.Time `bson:"expiresAt"`
+}
+
+// Current implements VersionStore.
+func (s *CollectionStore) Current(ctx context.Context) (VersionRecord, error) {
+	if err := s.createCollectionIfNotExist(ctx, s.collection); err != nil {
+		return VersionRecord{}, err
+	}
+
+	filter := stateFilter()
+	sort := bson.D{bson.E{Key: "_id", Value: -1}}
+	opts := options.FindOne().SetSort(sort)
+
+	// find record with the greatest id (assuming it`s latest also), failure records don't change version
+	result := s.db.Collection(s.collection).FindOne(ctx, filter, opts)
+	err := result.Err()
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return VersionRecord{}, nil
+	case err != nil:
+		return VersionRecord{}, err
+	}
+
+	var rec VersionRecord
+	if err := result.Decode(&rec); err != nil {
+		return VersionRecord{}, err
+	}
+
+	return rec, nil
+}
+
+// Record implements VersionStore.
+func (s *CollectionStore) Record(ctx context.Context, rec VersionRecord) error {
+	_, err := s.db.Collection(s.collection).InsertOne(ctx, rec)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// History implements VersionStore.
+func (s *CollectionStore) History(ctx context.Context, filter HistoryFilter) (records []VersionRecord, err error) {
+	if err := s.createCollectionIfNotExist(ctx, s.collection); err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if filter.Limit > 0 {
+		opts.SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(filter.Limit))
+	}
+	cursor, err := s.db.Collection(s.collection).Find(ctx, filter.query(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	if filter.Limit > 0 {
+		slices.Reverse(records)
+	}
+
+	return records, nil
+}
+
+// Lock implements VersionStore.
+func (s *CollectionStore) Lock(ctx context.Context, owner string, ttl time.Duration) error {
+	now := time.Now().UTC()
+	filter := bson.D{
+		{Key: "_id", Value: lockID},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "owner", Value: owner}},
+			bson.D{{Key: "expiresAt", Value: bson.D{{Key: "$lte", Value: now}}}},
+		}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "owner", Value: owner},
+		{Key: "acquiredAt", Value: now},
+		{Key: "expiresAt", Value: now.Add(ttl)},
+	}}}
+	opts := options.Update().SetUpsert(true)
+
+	// if lock is held by another owner filter doesn't match and upsert fails because of duplicate "_id"
+	_, err := s.db.Collection(s.lockCollection).UpdateOne(ctx, filter, update, opts)
+	switch {
+	case mongo.IsDuplicateKeyError(err):
+		return s.lockedError(ctx)
+	case err != nil:
+		return err
+	}
+	return nil
+}
+
+func (s *CollectionStore) lockedError(ctx context.Context) error {
+	var rec lockRecord
+	err := s.db.Collection(s.lockCollection).FindOne(ctx, bson.D{{Key: "_id", Value: lockID}}).Decode(&rec)
+	if err != nil {
+		return ErrLocked
+	}
+	return fmt.Errorf("%w: held by %q until %s", ErrLocked, rec.Owner, rec.ExpiresAt.Format(time.RFC3339))
+}
+
+// RefreshLock implements VersionStore.
+func (s *CollectionStore) RefreshLock(ctx context.Context, owner string, ttl time.Duration) error {
+	filter := bson.D{{Key: "_id", Value: lockID}, {Key: "owner", Value: owner}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "expiresAt", Value: time.Now().UTC().Add(ttl)}}}}
+
+	res, err := s.db.Collection(s.lockCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Unlock implements VersionStore.
+func (s *CollectionStore) Unlock(ctx context.Context, owner string) error {
+	filter := bson.D{{Key: "_id", Value: lockID}, {Key: "owner", Value: owner}}
+	_, err := s.db.Collection(s.lockCollection).DeleteOne(ctx, filter)
+	return err
+}
+
+func (s *CollectionStore) isCollectionExist(ctx context.Context, name string) (isExist bool, err error) {
+	collections, err := s.getCollections(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, c := range collections {
+		if name == c.Name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *CollectionStore) createCollectionIfNotExist(ctx context.Context, name string) error {
+	exist, err := s.isCollectionExist(ctx, name)
+	if err != nil {
+		return err
+	}
+	if exist {
+		return nil
+	}
+
+	command := bson.D{bson.E{Key: "create", Value: name}}
+	if err = s.db.RunCommand(ctx, command).Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *CollectionStore) getCollections(ctx context.Context) (collections []collectionSpecification, err error) {
+	cursor, err := s.db.ListCollections(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	if cursor != nil {
+		defer func(cursor *mongo.Cursor) {
+			curErr := cursor.Close(ctx)
+			if curErr != nil {
+				if err != nil {
+					err = fmt.Errorf("migrate: get collection failed: %w", err)
+				} else {
+					err = curErr
+				}
+			}
+		}(cursor)
+	}
+
+	for cursor.Next(ctx) {
+		var collection collectionSpecification
+
+		err := cursor.Decode(&collection)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(collection.Type) == 0 || collection.Type == "collection" {
+			collections = append(collections, collection)
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return
+}
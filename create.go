@@ -0,0 +1,207 @@
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// timestampVersionLayout is a layout of timestamp-based migration versions.
+const timestampVersionLayout = "20060102150405"
+
+var migrationTemplate = template.Must(template.New("migration").Parse(`package {{ .Package }}
+
+import (
+	"context"
+
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func init() {
+	migrate.MustRegister(func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	}, func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	})
+}
+`))
+
+// CreateOptions configures migration file generation.
+type CreateOptions struct {
+	// Timestamp makes version of new migration to be current UTC time in "YYYYMMDDhhmmss" format.
+	// By default, next version after the biggest existing one is used.
+	Timestamp bool
+	// Package is a package name of new file. By default, it is taken from existing Go files in directory
+	// or from directory name.
+	Package string
+}
+
+// Create generates new migration file named "<version>_<description>.go" in provided directory
+// with template for registering migration using MustRegister.
+// Description is converted to lower-case slug which doesn't make file a test or platform-specific one
+// (e.g. "test" becomes "test-migration"). Path of created file is returned.
+func Create(dir, description string, opts CreateOptions) (string, error) {
+	slug := slugify(description)
+	if slug == "" {
+		return "", fmt.Errorf("migrate: empty migration description")
+	}
+
+	versions, err := migrationFileVersions(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var version uint64
+	switch {
+	case opts.Timestamp:
+		version, _ = strconv.ParseUint(time.Now().UTC().Format(timestampVersionLayout), 10, 64)
+	default:
+		for v := range versions {
+			if v > version {
+				version = v
+			}
+		}
+		version++
+	}
+	if file, ok := versions[version]; ok {
+		return "", fmt.Errorf("migrate: migration with version %d already exists: %s", version, file)
+	}
+
+	pkg := opts.Package
+	if pkg == "" {
+		if pkg, err = packageName(dir); err != nil {
+			return "", err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := migrationTemplate.Execute(&buf, struct{ Package string }{Package: pkg}); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%d_%s.go", version, slug))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// reservedSlugSuffix is appended to slug which would make file a test file or restrict it to some platform.
+const reservedSlugSuffix = "-migration"
+
+// reservedSlugs are file name parts which Go treats as build constraints if they are the last "_"-separated part:
+// "test" and known GOOS and GOARCH values.
+var reservedSlugs = map[string]struct{}{
+	"test": {},
+
+	"aix": {}, "android": {}, "darwin": {}, "dragonfly": {}, "freebsd": {}, "hurd": {}, "illumos": {}, "ios": {},
+	"js": {}, "linux": {}, "nacl": {}, "netbsd": {}, "openbsd": {}, "plan9": {}, "solaris": {}, "wasip1": {},
+	"windows": {}, "zos": {},
+
+	"386": {}, "amd64": {}, "amd64p32": {}, "arm": {}, "armbe": {}, "arm64": {}, "arm64be": {}, "loong64": {},
+	"mips": {}, "mipsle": {}, "mips64": {}, "mips64le": {}, "mips64p32": {}, "mips64p32le": {}, "ppc": {},
+	"ppc64": {}, "ppc64le": {}, "riscv": {}, "riscv64": {}, "s390": {}, "s390x": {}, "sparc": {}, "sparc64": {},
+	"wasm": {},
+}
+
+// slugify converts description to file name part which will be extracted back as migration description.
+// Words are separated by "-" to not produce file names with build constraint suffixes (e.g. "_test" or "_linux").
+// Single word which is such suffix itself gets reservedSlugSuffix.
+func slugify(description string) string {
+	var b strings.Builder
+	pendingDash := false
+	for _, r := range strings.ToLower(description) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingDash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			pendingDash = false
+			continue
+		}
+		pendingDash = true
+	}
+
+	slug := b.String()
+	if _, ok := reservedSlugs[slug]; ok {
+		slug += reservedSlugSuffix
+	}
+	return slug
+}
+
+// migrationFileVersions returns versions of migration files (Go and declarative ones) in directory mapped to file names.
+func migrationFileVersions(dir string) (map[uint64]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[uint64]string)
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+		for _, ext := range []string{".go", upJSONExt, downJSONExt} {
+			version, _, err := extractVersionDescriptionExt(entry.Name(), ext)
+			if err != nil {
+				continue
+			}
+			ret[version] = entry.Name()
+			break
+		}
+	}
+	return ret, nil
+}
+
+// packageName returns package name of Go files in directory or directory name if there are no such files.
+func packageName(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, name), nil, parser.PackageClauseOnly)
+		if err != nil {
+			return "", err
+		}
+		return f.Name.Name, nil
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	pkg := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(filepath.Base(abs)))
+	if pkg == "" || unicode.IsDigit(rune(pkg[0])) {
+		pkg = "migrations"
+	}
+	return pkg, nil
+}
@@ -0,0 +1,108 @@
+package migrate
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	for description, expected := range map[string]string{
+		"Add my index":         "add-my-index",
+		"  users: drop _test ": "users-drop-test",
+		"!!!":                  "",
+		"Test":                 "test-migration",
+		"linux":                "linux-migration",
+		"amd64":                "amd64-migration",
+		"linux amd64":          "linux-amd64",
+	} {
+		if slug := slugify(description); slug != expected {
+			t.Errorf("Unexpected slug for %q: %q", description, slug)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+
+	path, err := Create(dir, "Add my index", CreateOptions{Package: "migrations"})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if filepath.Base(path) != "1_add-my-index.go" {
+		t.Errorf("Unexpected file: %v", path)
+	}
+	version, description, err := extractVersionDescription(path)
+	if err != nil || version != 1 || description != "add-my-index" {
+		t.Errorf("Unexpected version/description: %v %v %v", version, description, err)
+	}
+
+	path, err = Create(dir, "second", CreateOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if filepath.Base(path) != "2_second.go" {
+		t.Errorf("Unexpected file: %v", path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if !strings.HasPrefix(string(content), "package migrations\n") {
+		t.Errorf("Unexpected package: %s", content)
+	}
+
+	path, err = Create(dir, "third", CreateOptions{Timestamp: true})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if version, _, _ := extractVersionDescription(path); len(filepath.Base(path)) != len("20060102150405_third.go") || version < 20000101000000 {
+		t.Errorf("Unexpected file: %v", path)
+	}
+}
+
+func TestCreateReservedDescription(t *testing.T) {
+	dir := t.TempDir()
+
+	for i, description := range []string{"test", "windows"} {
+		path, err := Create(dir, description, CreateOptions{Package: "migrations"})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+
+		expected := fmt.Sprintf("%d_%s-migration.go", i+1, description)
+		if filepath.Base(path) != expected {
+			t.Errorf("Unexpected file: %v", path)
+		}
+		if match, err := build.Default.MatchFile(dir, filepath.Base(path)); err != nil || !match {
+			t.Errorf("File %v is not matched by build context: %v", path, err)
+		}
+	}
+}
+
+func TestCreateAfterDeclarativeMigrations(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"1_first.go", "2_second.up.json", "3_third.down.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package migrations\n"), 0o644); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+	}
+
+	path, err := Create(dir, "fourth", CreateOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if filepath.Base(path) != "4_fourth.go" {
+		t.Errorf("Unexpected file: %v", path)
+	}
+}
@@ -0,0 +1,91 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ErrNoMigrationData returned by LoadMigrationData if data with provided key was not saved when migration was applied.
+var ErrNoMigrationData = errors.New("migrate: migration data not found")
+
+// migrationContextKey is a context key of performed migration.
+type migrationContextKey struct{}
+
+// migrationContext describes migration performed by "Up", "Down" or "To" for helpers called from migration function.
+type migrationContext struct {
+	m           *Migrate
+	version     uint64
+	direction   Direction
+	checkpoints string
+	data        *bson.D
+}
+
+func (m *Migrate) withMigrationContext(ctx context.Context, migration Migration, direction Direction, data *bson.D) context.Context {
+	return context.WithValue(ctx, migrationContextKey{}, migrationContext{
+		m:           m,
+		version:     migration.Version,
+		direction:   direction,
+		checkpoints: m.migrationsCollection + defaultCheckpointsCollectionSuffix,
+		data:        data,
+	})
+}
+
+func currentMigration(ctx context.Context) (migrationContext, error) {
+	mc, ok := ctx.Value(migrationContextKey{}).(migrationContext)
+	if !ok {
+		return migrationContext{}, errors.New("migrate: context doesn't belong to performed migration")
+	}
+	return mc, nil
+}
+
+// SaveMigrationData saves value with provided key to history record of migration being performed,
+// e.g. previous state of changed object which is required to revert migration.
+// Value is saved only if migration succeeds. It can be loaded later in "down" function using LoadMigrationData.
+// Context must be the one passed to migration function.
+func SaveMigrationData(ctx context.Context, key string, value any) error {
+	mc, err := currentMigration(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i, e := range *mc.data {
+		if e.Key == key {
+			(*mc.data)[i].Value = value
+			return nil
+		}
+	}
+	*mc.data = append(*mc.data, bson.E{Key: key, Value: value})
+	return nil
+}
+
+// LoadMigrationData loads value saved with SaveMigrationData when migration was applied the last time.
+// ErrNoMigrationData is returned if there is no such value.
+// Context must be the one passed to migration function.
+func LoadMigrationData(ctx context.Context, key string, value any) error {
+	mc, err := currentMigration(ctx)
+	if err != nil {
+		return err
+	}
+
+	records, err := mc.m.versionStore().History(ctx, HistoryFilter{
+		Version:   mc.version,
+		Direction: DirectionUp,
+		Events:    []HistoryEvent{HistoryMigrated},
+		Limit:     1,
+	})
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 || records[0].Data == nil {
+		return fmt.Errorf("%w: %q of migration %d", ErrNoMigrationData, key, mc.version)
+	}
+
+	raw, err := records[0].Data.LookupErr(key)
+	if err != nil {
+		return fmt.Errorf("%w: %q of migration %d", ErrNoMigrationData, key, mc.version)
+	}
+	return raw.Unmarshal(value)
+}
@@ -0,0 +1,42 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestMigrationData(t *testing.T) {
+	ctx := context.Background()
+	var loaded string
+	migrate := NewMigrate(nil, Migration{Version: 1, Up: func(ctx context.Context, db *mongo.Database) error {
+		if err := SaveMigrationData(ctx, "previous", "old"); err != nil {
+			return err
+		}
+		return SaveMigrationData(ctx, "previous", "older")
+	}, Down: func(ctx context.Context, db *mongo.Database) error {
+		if err := LoadMigrationData(ctx, "unknown", &loaded); !errors.Is(err, ErrNoMigrationData) {
+			return err
+		}
+		return LoadMigrationData(ctx, "previous", &loaded)
+	}})
+	migrate.SetVersionStore(&memoryStore{})
+
+	if err := migrate.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := migrate.Down(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if loaded != "older" {
+		t.Errorf("Unexpected data: %q", loaded)
+	}
+
+	if err := SaveMigrationData(ctx, "key", "value"); err == nil {
+		t.Errorf("Unexpected nil error")
+	}
+}
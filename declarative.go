@@ -0,0 +1,165 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	upJSONExt   = ".up.json"
+	downJSONExt = ".down.json"
+)
+
+type declarativeFiles struct {
+	description string
+	up, down    []byte
+}
+
+// LoadFS loads declarative migrations from files in directory of provided file system.
+// Files should be named like "<version>_<description>.up.json" and "<version>_<description>.down.json",
+// "down" file is optional. Each file contains MongoDB command document or array of command documents
+// in Extended JSON format, e.g.:
+//
+//	[
+//		{"createIndexes": "my-coll", "indexes": [{"key": {"my-key": 1}, "name": "my-index"}]},
+//		{"update": "my-coll", "updates": [{"q": {}, "u": {"$set": {"flag": false}}, "multi": true}]}
+//	]
+//
+// Commands are executed using "RunCommand" one by one.
+// Returned migrations can be mixed with ones defined in code.
+func LoadFS(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[uint64]*declarativeFiles)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+
+		var ext string
+		switch {
+		case strings.HasSuffix(name, upJSONExt):
+			ext = upJSONExt
+		case strings.HasSuffix(name, downJSONExt):
+			ext = downJSONExt
+		default:
+			continue
+		}
+
+		version, description, err := extractVersionDescriptionExt(name, ext)
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		f, ok := files[version]
+		switch {
+		case !ok:
+			f = &declarativeFiles{description: description}
+			files[version] = f
+		case f.description != description:
+			return nil, fmt.Errorf("migrate: different descriptions for migration with version %d: %q and %q", version, f.description, description)
+		}
+		if ext == upJSONExt {
+			f.up = content
+		} else {
+			f.down = content
+		}
+	}
+
+	ret := make([]Migration, 0, len(files))
+	for version, f := range files {
+		migration, err := declarativeMigration(version, f)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, migration)
+	}
+	migrationSort(ret)
+
+	return ret, nil
+}
+
+func declarativeMigration(version uint64, f *declarativeFiles) (Migration, error) {
+	if f.up == nil {
+		return Migration{}, fmt.Errorf("migrate: no %q file for migration with version %d", upJSONExt, version)
+	}
+
+	up, err := parseCommands(f.up)
+	if err != nil {
+		return Migration{}, fmt.Errorf("migrate: parse %q file of migration %d: %w", upJSONExt, version, err)
+	}
+
+	sum := sha256.New()
+	sum.Write(f.up)
+
+	migration := Migration{
+		Version:     version,
+		Description: f.description,
+		Up:          runCommands(up),
+	}
+	if f.down != nil {
+		down, err := parseCommands(f.down)
+		if err != nil {
+			return Migration{}, fmt.Errorf("migrate: parse %q file of migration %d: %w", downJSONExt, version, err)
+		}
+		migration.Down = runCommands(down)
+		sum.Write(f.down)
+	}
+	migration.Checksum = hex.EncodeToString(sum.Sum(nil))
+
+	return migration, nil
+}
+
+// parseCommands parses Extended JSON command document or array of command documents.
+func parseCommands(content []byte) ([]bson.D, error) {
+	trimmed := bytes.TrimSpace(content)
+	if !bytes.HasPrefix(trimmed, []byte("[")) {
+		var command bson.D
+		if err := bson.UnmarshalExtJSON(trimmed, false, &command); err != nil {
+			return nil, err
+		}
+		return []bson.D{command}, nil
+	}
+
+	// top-level arrays are not supported by Extended JSON decoder, so wrap it into document
+	wrapped := make([]byte, 0, len(trimmed)+len(`{"commands":}`))
+	wrapped = append(wrapped, `{"commands":`...)
+	wrapped = append(wrapped, trimmed...)
+	wrapped = append(wrapped, '}')
+
+	var doc struct {
+		Commands []bson.D `bson:"commands"`
+	}
+	if err := bson.UnmarshalExtJSON(wrapped, false, &doc); err != nil {
+		return nil, err
+	}
+	return doc.Commands, nil
+}
+
+func runCommands(commands []bson.D) MigrationFunc {
+	return func(ctx context.Context, db *mongo.Database) error {
+		for i, command := range commands {
+			if err := db.RunCommand(ctx, command).Err(); err != nil {
+				return fmt.Errorf("command %d: %w", i, err)
+			}
+		}
+		return nil
+	}
+}
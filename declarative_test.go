@@ -0,0 +1,57 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/1_add-index.up.json":   {Data: []byte(`{"createIndexes": "test", "indexes": [{"key": {"a": 1}, "name": "a"}]}`)},
+		"migrations/1_add-index.down.json": {Data: []byte(`{"dropIndexes": "test", "index": "a"}`)},
+		"migrations/2_update.up.json": {Data: []byte(`[
+			{"update": "test", "updates": [{"q": {}, "u": {"$set": {"b": {"$numberLong": "1"}}}, "multi": true}]},
+			{"update": "test", "updates": [{"q": {}, "u": {"$unset": {"c": ""}}, "multi": true}]}
+		]`)},
+		"migrations/README.md": {Data: []byte("not a migration")},
+	}
+
+	migrations, err := LoadFS(fsys, "migrations")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(migrations) != 2 {
+		t.Errorf("Unexpected migrations count: %v", len(migrations))
+		return
+	}
+	if m := migrations[0]; m.Version != 1 || m.Description != "add-index" || m.Up == nil || m.Down == nil || m.Checksum == "" {
+		t.Errorf("Unexpected migration: %+v", m)
+	}
+	if m := migrations[1]; m.Version != 2 || m.Description != "update" || m.Up == nil || m.Down != nil {
+		t.Errorf("Unexpected migration: %+v", m)
+	}
+}
+
+func TestLoadFSErrors(t *testing.T) {
+	for name, fsys := range map[string]fstest.MapFS{
+		"no up file":   {"1_a.down.json": {Data: []byte(`{"ping": 1}`)}},
+		"bad json":     {"1_a.up.json": {Data: []byte(`{"ping": `)}},
+		"descriptions": {"1_a.up.json": {Data: []byte(`{"ping": 1}`)}, "1_b.down.json": {Data: []byte(`{"ping": 1}`)}},
+	} {
+		if _, err := LoadFS(fsys, "."); err == nil {
+			t.Errorf("Unexpected nil error for %s", name)
+		}
+	}
+}
+
+func TestParseCommands(t *testing.T) {
+	commands, err := parseCommands([]byte(` [{"ping": 1}, {"hello": 1}] `))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(commands) != 2 || commands[0][0].Key != "ping" || commands[1][0].Key != "hello" {
+		t.Errorf("Unexpected commands: %v", commands)
+	}
+}
@@ -0,0 +1,87 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DirtyRecord is a marker of migration which was started but not completed.
+type DirtyRecord struct {
+	Version     uint64    `bson:"version"`
+	Description string    `bson:"description,omitempty"`
+	Direction   Direction `bson:"direction"`
+	StartedAt   time.Time `bson:"startedAt"`
+}
+
+// DirtyError returned by "Up", "Down" and "To" when dirty state tracking is enabled
+// and previous migration was started but not completed (failed or process crashed).
+// Database may be in unknown state in this case, so fix it manually and call "Repair" or "SetVersion".
+type DirtyError struct {
+	Version     uint64
+	Description string
+	Direction   Direction
+	StartedAt   time.Time
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf("migrate: database is dirty, %s migration %d (%s) started at %s was not completed",
+		e.Direction, e.Version, e.Description, e.StartedAt.Format(time.RFC3339))
+}
+
+// SetDirtyTracking enables dirty state tracking.
+// Before performing not transactional migration marker record is written to history,
+// "Up", "Down" and "To" refuse to run while the latest record is such marker.
+func (m *Migrate) SetDirtyTracking(enabled bool) {
+	m.dirtyTracking = enabled
+}
+
+func (m *Migrate) checkDirty(state versionState) error {
+	if !m.dirtyTracking || state.dirty == nil {
+		return nil
+	}
+	return state.dirty.toError()
+}
+
+func (d *DirtyRecord) toError() *DirtyError {
+	return &DirtyError{
+		Version:     d.Version,
+		Description: d.Description,
+		Direction:   d.Direction,
+		StartedAt:   d.StartedAt,
+	}
+}
+
+// markDirty writes marker of started migration keeping current state.
+func (m *Migrate) markDirty(ctx context.Context, state versionState, s step) error {
+	rec := state.toRecord()
+	rec.Event = HistoryStarted
+	rec.Direction = s.direction
+	rec.MigrationVersion = s.migration.Version
+	rec.MigrationDescription = s.migration.Description
+	rec.Dirty = &DirtyRecord{
+		Version:     s.migration.Version,
+		Description: s.migration.Description,
+		Direction:   s.direction,
+		StartedAt:   time.Now().UTC(),
+	}
+	return m.insertRecord(ctx, rec)
+}
+
+// Repair clears dirty state after manual intervention.
+// Database state is restored to the one before started migration, so it is considered not performed.
+// Use SetVersion to set another version instead.
+func (m *Migrate) Repair(ctx context.Context) error {
+	return m.withLock(ctx, func(ctx context.Context) error {
+		state, err := m.state(ctx)
+		if err != nil {
+			return err
+		}
+		if state.dirty == nil {
+			return nil
+		}
+		rec := state.toRecord()
+		rec.Event = HistoryRepaired
+		return m.insertRecord(ctx, rec)
+	})
+}
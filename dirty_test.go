@@ -0,0 +1,43 @@
+package migrate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckDirty(t *testing.T) {
+	migrate := NewMigrate(nil, testMigrations(1, 2)...)
+	state := stateFromRecord(VersionRecord{
+		Version: 1,
+		Applied: []uint64{1},
+		Dirty:   &DirtyRecord{Version: 2, Direction: DirectionUp, StartedAt: time.Now()},
+	})
+
+	if _, err := migrate.planUp(state, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	migrate.SetDirtyTracking(true)
+	var dirtyErr *DirtyError
+	if _, err := migrate.planUp(state, AllAvailable); !errors.As(err, &dirtyErr) || dirtyErr.Version != 2 {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := migrate.planDown(state, AllAvailable); !errors.As(err, &dirtyErr) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestVersionStateToRecord(t *testing.T) {
+	rec := VersionRecord{Version: 3, Description: "c", Applied: []uint64{1, 3}, Checksums: map[string]string{"3": "sum"}}
+	if converted := stateFromRecord(rec).toRecord(); !reflect.DeepEqual(converted, rec) {
+		t.Errorf("Unexpected record: %+v", converted)
+	}
+
+	// legacy records must stay legacy
+	rec = VersionRecord{Version: 3, Description: "c"}
+	if converted := stateFromRecord(rec).toRecord(); !reflect.DeepEqual(converted, rec) {
+		t.Errorf("Unexpected record: %+v", converted)
+	}
+}
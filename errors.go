@@ -0,0 +1,36 @@
+package migrate
+
+import "fmt"
+
+// MigrationPhase is a phase of performing migration.
+type MigrationPhase string
+
+const (
+	// PhaseRun means calling migration function.
+	PhaseRun MigrationPhase = "run"
+	// PhaseRecord means writing migration history record.
+	PhaseRecord MigrationPhase = "record"
+	// PhaseTransaction means starting or committing transaction of transactional migration.
+	PhaseTransaction MigrationPhase = "transaction"
+)
+
+// MigrationError returned by "Up", "Down" and "To" when migration fails.
+// Underlying error is available using errors.Is, errors.As or Unwrap.
+type MigrationError struct {
+	Version     uint64
+	Description string
+	Direction   Direction
+	Phase       MigrationPhase
+	// LastVersion is a database version after last successfully performed migration.
+	LastVersion uint64
+	Err         error
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("migrate: %s migration %d (%s) failed in %s phase (database version %d): %v",
+		e.Direction, e.Version, e.Description, e.Phase, e.LastVersion, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,39 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestMigrationError(t *testing.T) {
+	expectedErr := errors.New("normal error")
+	migrate := NewMigrate(nil,
+		Migration{Version: 3, Description: "broken", Down: func(ctx context.Context, db *mongo.Database) error {
+			return expectedErr
+		}},
+	)
+	state := stateFromRecord(VersionRecord{Version: 3, Applied: []uint64{3}})
+
+	steps, err := migrate.planDown(state, AllAvailable)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	err = migrate.execute(context.Background(), state, steps)
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	var migrationErr *MigrationError
+	if !errors.As(err, &migrationErr) {
+		t.Errorf("Unexpected error type: %T", err)
+		return
+	}
+	if migrationErr.Version != 3 || migrationErr.Description != "broken" || migrationErr.Direction != DirectionDown ||
+		migrationErr.Phase != PhaseRun || migrationErr.LastVersion != 3 {
+		t.Errorf("Unexpected error: %+v", migrationErr)
+	}
+}
@@ -3,7 +3,10 @@ package migrate
 import (
 	"context"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"runtime"
+	"time"
 
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
@@ -16,15 +19,20 @@ func internalRegister(up, down MigrationFunc, skip int) error {
 	if err != nil {
 		return err
 	}
-	if hasVersion(globalMigrate.migrations, version) {
-		return fmt.Errorf("migration with version %v already registered", version)
-	}
-	globalMigrate.migrations = append(globalMigrate.migrations, Migration{
+	return registerMigration(Migration{
 		Version:     version,
 		Description: description,
 		Up:          up,
 		Down:        down,
+		Checksum:    fileChecksum(file),
 	})
+}
+
+func registerMigration(migration Migration) error {
+	if hasVersion(globalMigrate.migrations, migration.Version) {
+		return fmt.Errorf("migration with version %v already registered", migration.Version)
+	}
+	globalMigrate.migrations = append(globalMigrate.migrations, migration)
 	return nil
 }
 
@@ -73,6 +81,49 @@ func MustRegister(up, down MigrationFunc) {
 	}
 }
 
+// RegisterVersion performs migration registration with explicitly provided version and description.
+// Unlike Register it doesn't depend on name of file where it is called,
+// so it can be used in helpers, generated code or binaries built with "-trimpath".
+func RegisterVersion(version uint64, description string, up, down MigrationFunc) error {
+	return registerMigration(Migration{
+		Version:     version,
+		Description: description,
+		Up:          up,
+		Down:        down,
+	})
+}
+
+// MustRegisterVersion acts like RegisterVersion but panics on errors.
+func MustRegisterVersion(version uint64, description string, up, down MigrationFunc) {
+	if err := RegisterVersion(version, description, up, down); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterFS performs registration of declarative migrations from directory of provided file system
+// (e.g. embed.FS). Version and description are taken from file names.
+// Detailed description of files format available in LoadFS().
+func RegisterFS(fsys fs.FS, dir string) error {
+	migrations, err := LoadFS(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if hasVersion(globalMigrate.migrations, migration.Version) {
+			return fmt.Errorf("migration with version %v already registered", migration.Version)
+		}
+	}
+	globalMigrate.migrations = append(globalMigrate.migrations, migrations...)
+	return nil
+}
+
+// MustRegisterFS acts like RegisterFS but panics on errors.
+func MustRegisterFS(fsys fs.FS, dir string) {
+	if err := RegisterFS(fsys, dir); err != nil {
+		panic(err)
+	}
+}
+
 // RegisteredMigrations returns all registered migrations.
 func RegisteredMigrations() []Migration {
 	ret := make([]Migration, len(globalMigrate.migrations))
@@ -111,3 +162,115 @@ func Down(ctx context.Context, n int) error {
 func SetLogger(log Logger) {
 	globalMigrate.SetLogger(log)
 }
+
+// SetLockOptions enables locking of migrations for global migrate.
+// Detailed description available in Migrate.SetLockOptions().
+func SetLockOptions(opts *LockOptions) {
+	globalMigrate.SetLockOptions(opts)
+}
+
+// SetLockCollection changes default collection name for migrations lock.
+func SetLockCollection(name string) {
+	globalMigrate.SetLockCollection(name)
+}
+
+// SetTransactional makes all registered migrations run in transactions.
+// Detailed description available in Migrate.SetTransactional().
+func SetTransactional(transactional bool) {
+	globalMigrate.SetTransactional(transactional)
+}
+
+// Status returns status of registered migrations.
+// Detailed description available in Migrate.Status().
+func Status(ctx context.Context) (*StatusReport, error) {
+	return globalMigrate.Status(ctx)
+}
+
+// To migrates database to provided version using registered migrations.
+// Detailed description available in Migrate.To().
+func To(ctx context.Context, target uint64) error {
+	return globalMigrate.To(ctx, target)
+}
+
+// SetOutOfOrderPolicy sets policy of handling not applied registered migrations with versions lower than current database version.
+// Detailed description available in Migrate.SetOutOfOrderPolicy().
+func SetOutOfOrderPolicy(policy OutOfOrderPolicy) {
+	globalMigrate.SetOutOfOrderPolicy(policy)
+}
+
+// SetValidateChecksums enables validation of registered migrations checksums.
+// Detailed description available in Migrate.SetValidateChecksums().
+func SetValidateChecksums(validate bool) {
+	globalMigrate.SetValidateChecksums(validate)
+}
+
+// PlanUp returns migrations which would be applied by Up.
+// Detailed description available in Migrate.PlanUp().
+func PlanUp(ctx context.Context, n int) (*Plan, error) {
+	return globalMigrate.PlanUp(ctx, n)
+}
+
+// PlanDown returns migrations which would be reverted by Down.
+// Detailed description available in Migrate.PlanDown().
+func PlanDown(ctx context.Context, n int) (*Plan, error) {
+	return globalMigrate.PlanDown(ctx, n)
+}
+
+// PlanTo returns migrations which would be performed by To.
+// Detailed description available in Migrate.PlanTo().
+func PlanTo(ctx context.Context, target uint64) (*Plan, error) {
+	return globalMigrate.PlanTo(ctx, target)
+}
+
+// SetHooks sets hooks receiving migration process events for global migrate.
+func SetHooks(hooks Hooks) {
+	globalMigrate.SetHooks(hooks)
+}
+
+// SetSlogLogger sets structured logger to log the migration process of global migrate.
+// Detailed description available in Migrate.SetSlogLogger().
+func SetSlogLogger(logger *slog.Logger) {
+	globalMigrate.SetSlogLogger(logger)
+}
+
+// SetDirtyTracking enables dirty state tracking for global migrate.
+// Detailed description available in Migrate.SetDirtyTracking().
+func SetDirtyTracking(enabled bool) {
+	globalMigrate.SetDirtyTracking(enabled)
+}
+
+// Repair clears dirty state after manual intervention.
+// Detailed description available in Migrate.Repair().
+func Repair(ctx context.Context) error {
+	return globalMigrate.Repair(ctx)
+}
+
+// SetMigrationTimeout sets default timeout for each registered migration.
+// Detailed description available in Migrate.SetMigrationTimeout().
+func SetMigrationTimeout(timeout time.Duration) {
+	globalMigrate.SetMigrationTimeout(timeout)
+}
+
+// History returns entries of migrations history matching filter.
+// Detailed description available in Migrate.History().
+func History(ctx context.Context, filter HistoryFilter) ([]HistoryEntry, error) {
+	return globalMigrate.History(ctx, filter)
+}
+
+// SetOperator sets string identifying who performs migrations for global migrate.
+// Detailed description available in Migrate.SetOperator().
+func SetOperator(operator string) {
+	globalMigrate.SetOperator(operator)
+}
+
+// SetApplication sets name of application performing migrations for global migrate.
+// Detailed description available in Migrate.SetApplication().
+func SetApplication(application string) {
+	globalMigrate.SetApplication(application)
+}
+
+// SetVersionStore replaces storage of migrations history and lock for global migrate.
+// Detailed description available in Migrate.SetVersionStore().
+func SetVersionStore(store VersionStore) {
+	globalMigrate.SetVersionStore(store)
+}
@@ -1,6 +1,6 @@
 module github.com/xakep666/mongo-migrate
 
-go 1.20
+go 1.21
 
 require go.mongodb.org/mongo-driver/v2 v2.0.0-beta2
 
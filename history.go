@@ -0,0 +1,235 @@
+package migrate
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// modulePath is used to find library version in build information.
+const modulePath = "github.com/xakep666/mongo-migrate"
+
+// failureRecordTimeout limits writing of failure record after context passed to "Up", "Down" or "To" is done.
+const failureRecordTimeout = 10 * time.Second
+
+// HistoryEvent is a kind of action which produced history entry.
+type HistoryEvent string
+
+const (
+	// HistoryMigrated means that migration was performed successfully.
+	HistoryMigrated HistoryEvent = "migrated"
+	// HistoryStarted is a marker of started migration written when dirty state tracking is enabled.
+	HistoryStarted HistoryEvent = "started"
+	// HistoryFailed means that migration failed. Such entries don't change database version.
+	HistoryFailed HistoryEvent = "failed"
+	// HistoryForced means that version was forcibly set by "SetVersion".
+	HistoryForced HistoryEvent = "forced"
+	// HistoryRepaired means that dirty state was cleared by "Repair".
+	HistoryRepaired HistoryEvent = "repaired"
+)
+
+// HistoryEntry is a single record of migrations history.
+// Records written by older versions of this package contain only version, description and timestamp.
+type HistoryEntry struct {
+	Event     HistoryEvent
+	Direction Direction
+	// MigrationVersion and MigrationDescription identify performed migration.
+	MigrationVersion     uint64
+	MigrationDescription string
+	// Version and Description describe database version after entry.
+	Version     uint64
+	Description string
+	Timestamp   time.Time
+	// Duration is a duration of migration function call.
+	Duration time.Duration
+	// Host, PID and Application identify process which wrote entry.
+	Host        string
+	PID         int
+	Application string
+	// Operator is a value set by "SetOperator".
+	Operator string
+	// LibraryVersion is a version of this package used by process which wrote entry.
+	LibraryVersion string
+	// Error is a text of migration error for failed entries.
+	Error string
+}
+
+// HistoryFilter limits entries returned by "History". Zero value matches all entries.
+type HistoryFilter struct {
+	// Version matches entries of migration with provided version.
+	Version uint64
+	// Direction matches entries of migrations performed in provided direction.
+	Direction Direction
+	// Events matches entries with one of provided events.
+	Events []HistoryEvent
+	// Since and Until match entries written in provided time range.
+	Since, Until time.Time
+	// Limit makes only latest Limit matched entries returned if positive.
+	Limit int
+}
+
+func (f HistoryFilter) query() bson.D {
+	query := bson.D{}
+	if f.Version != 0 {
+		query = append(query, bson.E{Key: "migrationVersion", Value: f.Version})
+	}
+	if f.Direction != "" {
+		query = append(query, bson.E{Key: "direction", Value: f.Direction})
+	}
+	if len(f.Events) > 0 {
+		query = append(query, bson.E{Key: "event", Value: bson.D{{Key: "$in", Value: f.Events}}})
+	}
+	var timestamp bson.D
+	if !f.Since.IsZero() {
+		timestamp = append(timestamp, bson.E{Key: "$gte", Value: f.Since})
+	}
+	if !f.Until.IsZero() {
+		timestamp = append(timestamp, bson.E{Key: "$lt", Value: f.Until})
+	}
+	if timestamp != nil {
+		query = append(query, bson.E{Key: "timestamp", Value: timestamp})
+	}
+	return query
+}
+
+// History returns entries of migrations history matching filter in order of writing.
+func (m *Migrate) History(ctx context.Context, filter HistoryFilter) ([]HistoryEntry, error) {
+	records, err := m.versionStore().History(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]HistoryEntry, 0, len(records))
+	for _, rec := range records {
+		entries = append(entries, rec.toEntry())
+	}
+	return entries, nil
+}
+
+func (rec VersionRecord) toEntry() HistoryEntry {
+	return HistoryEntry{
+		Event:                rec.Event,
+		Direction:            rec.Direction,
+		MigrationVersion:     rec.MigrationVersion,
+		MigrationDescription: rec.MigrationDescription,
+		Version:              rec.Version,
+		Description:          rec.Description,
+		Timestamp:            rec.Timestamp,
+		Duration:             time.Duration(rec.DurationMS) * time.Millisecond,
+		Host:                 rec.Host,
+		PID:                  rec.PID,
+		Application:          rec.Application,
+		Operator:             rec.Operator,
+		LibraryVersion:       rec.LibraryVersion,
+		Error:                rec.Error,
+	}
+}
+
+// SetOperator sets string identifying who performs migrations (e.g. user name or CI job), it is stored in history.
+func (m *Migrate) SetOperator(operator string) {
+	m.operator = operator
+}
+
+// SetApplication sets name of application performing migrations which is stored in history.
+// By default, it is a name of executable.
+func (m *Migrate) SetApplication(application string) {
+	m.application = application
+}
+
+// processInfo describes process writing history records.
+type processInfo struct {
+	host           string
+	pid            int
+	application    string
+	libraryVersion string
+}
+
+var currentProcess = sync.OnceValue(func() processInfo {
+	host, _ := os.Hostname()
+	info := processInfo{
+		host:        host,
+		pid:         os.Getpid(),
+		application: filepath.Base(os.Args[0]),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.libraryVersion = libraryVersion(bi)
+	}
+	return info
+})
+
+// libraryVersion returns version of this package from build information.
+func libraryVersion(bi *debug.BuildInfo) string {
+	if bi.Main.Path == modulePath {
+		return bi.Main.Version
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+		if dep.Replace != nil && dep.Replace.Version != "" {
+			return dep.Replace.Version
+		}
+		return dep.Version
+	}
+	return ""
+}
+
+// fillAudit sets fields identifying process which writes record.
+func (m *Migrate) fillAudit(rec *VersionRecord) {
+	info := currentProcess()
+	rec.Host = info.host
+	rec.PID = info.pid
+	rec.Application = info.application
+	if m.application != "" {
+		rec.Application = m.application
+	}
+	rec.Operator = m.operator
+	rec.LibraryVersion = info.libraryVersion
+}
+
+// recordFailure writes history record of failed migration. Such record doesn't change database version.
+// It is written even if context is already done, error of writing is only logged.
+func (m *Migrate) recordFailure(ctx context.Context, state versionState, s step, duration time.Duration, migrationErr error) {
+	if m.store == nil && m.db == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), failureRecordTimeout)
+	defer cancel()
+
+	rec := VersionRecord{
+		Version:              state.Version,
+		Description:          state.Description,
+		Event:                HistoryFailed,
+		Direction:            s.direction,
+		MigrationVersion:     s.migration.Version,
+		MigrationDescription: s.migration.Description,
+		DurationMS:           duration.Milliseconds(),
+		Error:                migrationErr.Error(),
+	}
+	if err := m.insertRecord(ctx, rec); err != nil {
+		m.logAttrs(ctx, slog.LevelWarn, "failed to record migration failure", slog.Any("error", err))
+		m.printf("Failed to record migration %d failure: %v", s.migration.Version, err)
+	}
+}
+
+// stateFilter matches records changing database version.
+func stateFilter() bson.D {
+	return bson.D{{Key: "event", Value: bson.D{{Key: "$ne", Value: HistoryFailed}}}}
+}
+
+// auditRecord returns step record describing performed migration.
+func (s step) auditRecord() VersionRecord {
+	rec := s.record
+	rec.Event = HistoryMigrated
+	rec.Direction = s.direction
+	rec.MigrationVersion = s.migration.Version
+	rec.MigrationDescription = s.migration.Description
+	return rec
+}
@@ -0,0 +1,84 @@
+package migrate
+
+import (
+	"reflect"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestHistoryFilterQuery(t *testing.T) {
+	if query := (HistoryFilter{}).query(); len(query) != 0 {
+		t.Errorf("Unexpected query: %v", query)
+	}
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	query := HistoryFilter{
+		Version:   2,
+		Direction: DirectionDown,
+		Events:    []HistoryEvent{HistoryFailed},
+		Since:     since,
+		Limit:     5,
+	}.query()
+	expected := bson.D{
+		{Key: "migrationVersion", Value: uint64(2)},
+		{Key: "direction", Value: DirectionDown},
+		{Key: "event", Value: bson.D{{Key: "$in", Value: []HistoryEvent{HistoryFailed}}}},
+		{Key: "timestamp", Value: bson.D{{Key: "$gte", Value: since}}},
+	}
+	if !reflect.DeepEqual(query, expected) {
+		t.Errorf("Unexpected query: %v", query)
+	}
+}
+
+func TestStepAuditRecord(t *testing.T) {
+	migrate := NewMigrate(nil, testMigrations(1, 2)...)
+	state := stateFromRecord(VersionRecord{Version: 2, Applied: []uint64{1, 2}})
+
+	steps, err := migrate.planDown(state, 1)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	rec := steps[0].auditRecord()
+	if rec.Event != HistoryMigrated || rec.Direction != DirectionDown || rec.MigrationVersion != 2 || rec.Version != 1 {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+}
+
+func TestFillAudit(t *testing.T) {
+	migrate := NewMigrate(nil)
+	migrate.SetOperator("ci")
+	migrate.SetApplication("my-app")
+
+	var rec VersionRecord
+	migrate.fillAudit(&rec)
+	if rec.Operator != "ci" || rec.Application != "my-app" || rec.PID == 0 {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+}
+
+func TestLibraryVersion(t *testing.T) {
+	bi := &debug.BuildInfo{
+		Main: debug.Module{Path: "example.com/app"},
+		Deps: []*debug.Module{
+			{Path: "example.com/other", Version: "v0.1.0"},
+			{Path: modulePath, Version: "v1.2.3"},
+		},
+	}
+	if v := libraryVersion(bi); v != "v1.2.3" {
+		t.Errorf("Unexpected version: %q", v)
+	}
+
+	bi.Deps[1].Replace = &debug.Module{Path: "example.com/fork", Version: "v1.2.4"}
+	if v := libraryVersion(bi); v != "v1.2.4" {
+		t.Errorf("Unexpected version: %q", v)
+	}
+
+	bi.Deps = nil
+	if v := libraryVersion(bi); v != "" {
+		t.Errorf("Unexpected version: %q", v)
+	}
+}
@@ -0,0 +1,57 @@
+package migrate
+
+import (
+	"context"
+	"time"
+)
+
+// MigrationEvent describes single migration being performed.
+type MigrationEvent struct {
+	Migration Migration
+	Direction Direction
+	// Version is a database version after successful performing of migration.
+	Version uint64
+	// Duration is a time spent on performing migration. It is zero in BeforeMigration.
+	Duration time.Duration
+}
+
+// Hooks receives events of migration process performed by "Up", "Down" and "To".
+// It can be used to collect metrics, send notifications or take snapshots.
+// Embed NopHooks to implement only needed methods.
+type Hooks interface {
+	// BeforeRun called before performing planned migrations. Returned error aborts run.
+	BeforeRun(ctx context.Context, plan *Plan) error
+	// AfterRun called after run finished with its result.
+	AfterRun(ctx context.Context, plan *Plan, err error)
+	// BeforeMigration called before performing each migration. Returned error aborts run.
+	BeforeMigration(ctx context.Context, event MigrationEvent) error
+	// AfterMigration called after migration was successfully performed and recorded.
+	AfterMigration(ctx context.Context, event MigrationEvent)
+	// OnError called when migration or its record failed.
+	OnError(ctx context.Context, event MigrationEvent, err error)
+}
+
+// NopHooks implements Hooks doing nothing.
+type NopHooks struct{}
+
+func (NopHooks) BeforeRun(context.Context, *Plan) error { return nil }
+
+func (NopHooks) AfterRun(context.Context, *Plan, error) {}
+
+func (NopHooks) BeforeMigration(context.Context, MigrationEvent) error { return nil }
+
+func (NopHooks) AfterMigration(context.Context, MigrationEvent) {}
+
+func (NopHooks) OnError(context.Context, MigrationEvent, error) {}
+
+// SetHooks sets hooks receiving migration process events.
+func (m *Migrate) SetHooks(hooks Hooks) {
+	m.hooks = hooks
+}
+
+func (m *Migrate) getHooks() Hooks {
+	if m.hooks == nil {
+		return NopHooks{}
+	}
+	return m.hooks
+}
@@ -0,0 +1,60 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type recordingHooks struct {
+	NopHooks
+	events []string
+	runErr error
+}
+
+func (h *recordingHooks) BeforeRun(ctx context.Context, plan *Plan) error {
+	h.events = append(h.events, "before run")
+	return nil
+}
+
+func (h *recordingHooks) AfterRun(ctx context.Context, plan *Plan, err error) {
+	h.events = append(h.events, "after run")
+	h.runErr = err
+}
+
+func (h *recordingHooks) BeforeMigration(ctx context.Context, event MigrationEvent) error {
+	h.events = append(h.events, "before "+string(event.Direction))
+	return nil
+}
+
+func (h *recordingHooks) OnError(ctx context.Context, event MigrationEvent, err error) {
+	h.events = append(h.events, "error "+string(event.Direction))
+}
+
+func TestHooksOnError(t *testing.T) {
+	expectedErr := errors.New("normal error")
+	migrate := NewMigrate(nil, Migration{Version: 1, Up: func(ctx context.Context, db *mongo.Database) error {
+		return expectedErr
+	}})
+	hooks := &recordingHooks{}
+	migrate.SetHooks(hooks)
+
+	steps, err := migrate.planUp(versionState{}, AllAvailable)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := migrate.execute(context.Background(), versionState{}, steps); !errors.Is(err, expectedErr) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if expected := []string{"before run", "before up", "error up", "after run"}; !reflect.DeepEqual(hooks.events, expected) {
+		t.Errorf("Unexpected events: %v", hooks.events)
+	}
+	if !errors.Is(hooks.runErr, expectedErr) {
+		t.Errorf("Unexpected run error: %v", hooks.runErr)
+	}
+}
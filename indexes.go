@@ -0,0 +1,184 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// indexesDataKey is a key of migration data with performed index changes.
+const indexesDataKey = "indexes"
+
+// IndexChange describes index created or dropped by migration built with IndexChanges or IndexMigration.
+type IndexChange struct {
+	collection string
+	create     *mongo.IndexModel
+	drop       string
+}
+
+// CreateIndex describes index to be created in collection. It is dropped on revert.
+// Any index options (partial filter, TTL, unique, collation, etc.) can be used.
+func CreateIndex(collection string, model mongo.IndexModel) IndexChange {
+	return IndexChange{collection: collection, create: &model}
+}
+
+// DropIndex describes index of collection to be dropped. Its specification is read before dropping
+// and stored in migrations history, so index is recreated with the same options on revert.
+// Index which doesn't exist is skipped, so "up" can be repeated if history record of previous attempt
+// was not written, but such index is not recreated on revert as its specification was lost.
+func DropIndex(collection, name string) IndexChange {
+	return IndexChange{collection: collection, drop: name}
+}
+
+// errIndexNotFound returned by indexSpecification if index doesn't exist.
+var errIndexNotFound = errors.New("migrate: index not found")
+
+// indexChangeRecord describes performed index change in migration data.
+type indexChangeRecord struct {
+	Collection string `bson:"collection"`
+	// Created is a name of created index.
+	Created string `bson:"created,omitempty"`
+	// Dropped is a specification of dropped index.
+	Dropped bson.D `bson:"dropped,omitempty"`
+}
+
+// IndexChanges returns "up" function performing index changes in provided order and "down" function reverting them
+// (created indexes are dropped, dropped ones are recreated with captured specifications) in reverse order.
+// If "up" fails, already performed changes are reverted. Functions must be called by Migrate (directly or using
+// migratetest.VerifyRoundTrip) because changes are stored in migrations history.
+// Functions can be passed to Register directly:
+//
+//	func init() {
+//		migrate.MustRegister(migrate.IndexChanges(
+//			migrate.CreateIndex("my-coll", mongo.IndexModel{
+//				Keys:    bson.D{{Key: "expires", Value: 1}},
+//				Options: options.Index().SetName("my-ttl-index").SetExpireAfterSeconds(3600),
+//			}),
+//			migrate.DropIndex("my-coll", "my-index"),
+//		))
+//	}
+func IndexChanges(changes ...IndexChange) (up, down MigrationFunc) {
+	up = func(ctx context.Context, db *mongo.Database) error {
+		// performed changes can not be saved outside of migration, so fail before applying them
+		if _, err := currentMigration(ctx); err != nil {
+			return err
+		}
+
+		var performed []indexChangeRecord
+		for _, change := range changes {
+			rec, err := change.apply(ctx, db)
+			if err != nil {
+				if revertErr := revertIndexChanges(ctx, db, performed); revertErr != nil {
+					return errors.Join(err, fmt.Errorf("migrate: revert index changes: %w", revertErr))
+				}
+				return err
+			}
+			performed = append(performed, rec)
+		}
+		return SaveMigrationData(ctx, indexesDataKey, performed)
+	}
+	down = func(ctx context.Context, db *mongo.Database) error {
+		var performed []indexChangeRecord
+		if err := LoadMigrationData(ctx, indexesDataKey, &performed); err != nil {
+			return err
+		}
+		return revertIndexChanges(ctx, db, performed)
+	}
+	return up, down
+}
+
+// IndexMigration builds migration performing index changes. Detailed description available in IndexChanges.
+func IndexMigration(version uint64, description string, changes ...IndexChange) Migration {
+	up, down := IndexChanges(changes...)
+	return Migration{Version: version, Description: description, Up: up, Down: down}
+}
+
+func (c IndexChange) apply(ctx context.Context, db *mongo.Database) (indexChangeRecord, error) {
+	coll := db.Collection(c.collection)
+	rec := indexChangeRecord{Collection: c.collection}
+
+	if c.create != nil {
+		name, err := coll.Indexes().CreateOne(ctx, *c.create)
+		if err != nil {
+			return rec, fmt.Errorf("migrate: create index of collection %q: %w", c.collection, err)
+		}
+		rec.Created = name
+		return rec, nil
+	}
+
+	spec, err := indexSpecification(ctx, coll, c.drop)
+	if errors.Is(err, errIndexNotFound) {
+		// already dropped by previous attempt
+		return rec, nil
+	}
+	if err != nil {
+		return rec, err
+	}
+	if err := coll.Indexes().DropOne(ctx, c.drop); err != nil {
+		return rec, fmt.Errorf("migrate: drop index %q of collection %q: %w", c.drop, c.collection, err)
+	}
+	rec.Dropped = spec
+	return rec, nil
+}
+
+// indexSpecification returns specification of index as reported by server without fields which are not index options.
+func indexSpecification(ctx context.Context, coll *mongo.Collection, name string) (bson.D, error) {
+	cursor, err := coll.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []bson.D
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return nil, err
+	}
+
+	for _, index := range indexes {
+		var (
+			spec      bson.D
+			indexName string
+		)
+		for _, e := range index {
+			switch e.Key {
+			case "v", "ns":
+				// index version and namespace are set by server
+				continue
+			case "name":
+				indexName, _ = e.Value.(string)
+			}
+			spec = append(spec, e)
+		}
+		if indexName == name {
+			return spec, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q of collection %q", errIndexNotFound, name, coll.Name())
+}
+
+func revertIndexChanges(ctx context.Context, db *mongo.Database, performed []indexChangeRecord) error {
+	for i := len(performed) - 1; i >= 0; i-- {
+		rec := performed[i]
+		if rec.Created != "" {
+			if err := db.Collection(rec.Collection).Indexes().DropOne(ctx, rec.Created); err != nil {
+				return fmt.Errorf("migrate: drop index %q of collection %q: %w", rec.Created, rec.Collection, err)
+			}
+			continue
+		}
+		if rec.Dropped == nil {
+			// index didn't exist when migration was applied
+			continue
+		}
+
+		command := bson.D{
+			{Key: "createIndexes", Value: rec.Collection},
+			{Key: "indexes", Value: bson.A{rec.Dropped}},
+		}
+		if err := db.RunCommand(ctx, command).Err(); err != nil {
+			return fmt.Errorf("migrate: recreate index of collection %q: %w", rec.Collection, err)
+		}
+	}
+	return nil
+}
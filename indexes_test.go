@@ -0,0 +1,32 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestIndexMigration(t *testing.T) {
+	migration := IndexMigration(3, "indexes",
+		CreateIndex("coll", mongo.IndexModel{Keys: bson.D{{Key: "a", Value: 1}}}),
+		DropIndex("coll", "b_1"),
+	)
+	if migration.Version != 3 || migration.Description != "indexes" || migration.Up == nil || migration.Down == nil {
+		t.Errorf("Unexpected migration: %+v", migration)
+	}
+
+	change := DropIndex("coll", "b_1")
+	if change.collection != "coll" || change.drop != "b_1" || change.create != nil {
+		t.Errorf("Unexpected change: %+v", change)
+	}
+}
+
+func TestIndexChangesOutsideMigration(t *testing.T) {
+	up, _ := IndexChanges(CreateIndex("coll", mongo.IndexModel{Keys: bson.D{{Key: "a", Value: 1}}}))
+	// database is not touched, so nil one doesn't cause panic
+	if err := up(context.Background(), nil); err == nil {
+		t.Errorf("Expected error")
+	}
+}
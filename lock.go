@@ -0,0 +1,209 @@
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// ErrLocked returned by "Up" and "Down" when migrations lock is held by another owner
+// and it can not be acquired within LockOptions.WaitTimeout.
+var ErrLocked = errors.New("migrate: migrations are locked by another process")
+
+// ErrLockLost returned when lock lease was not renewed in time and was taken over or removed.
+// Migration that was running at this moment is interrupted by context cancellation.
+var ErrLockLost = errors.New("migrate: migrations lock lost")
+
+const (
+	defaultLockCollectionSuffix = "_lock"
+
+	defaultLockTTL           = time.Minute
+	defaultLockRetryInterval = time.Second
+
+	// unlockTimeout limits lock release after context passed to "Up", "Down" or "To" is done.
+	unlockTimeout = 10 * time.Second
+)
+
+// LockOptions configures lease-based lock taken by "Up" and "Down" before running migrations.
+// Lock is a single document in dedicated collection which stores owner id and lease expiration time.
+// While migrations are running lease is renewed in background.
+// Expiration is computed using local clock, so clocks of lock contenders should be reasonably synchronized.
+type LockOptions struct {
+	// Owner identifies lock holder. By default, it is generated from host name, process id and random suffix.
+	Owner string
+	// TTL is a lease duration. Lock held by crashed process will be released after this time. Default is 1 minute.
+	TTL time.Duration
+	// RefreshInterval is an interval of lease renewal. Default is TTL/3.
+	RefreshInterval time.Duration
+	// WaitTimeout is a maximum time to wait for lock held by another owner.
+	// If it is zero, ErrLocked returned immediately. If it is negative, wait until context is done.
+	WaitTimeout time.Duration
+	// RetryInterval is an interval between lock acquisition attempts while waiting. Default is 1 second.
+	RetryInterval time.Duration
+}
+
+func (o LockOptions) withDefaults() LockOptions {
+	if o.Owner == "" {
+		o.Owner = defaultLockOwner()
+	}
+	if o.TTL <= 0 {
+		o.TTL = defaultLockTTL
+	}
+	if o.RefreshInterval <= 0 || o.RefreshInterval >= o.TTL {
+		o.RefreshInterval = o.TTL / 3
+	}
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = defaultLockRetryInterval
+	}
+	return o
+}
+
+func defaultLockOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	var suffix [4]byte
+	_, _ = rand.Read(suffix[:])
+
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(suffix[:]))
+}
+
+// SetLockOptions enables locking of migrations with provided options.
+// Passing nil disables locking (default).
+func (m *Migrate) SetLockOptions(opts *LockOptions) {
+	if opts == nil {
+		m.lockOpts = nil
+		return
+	}
+	o := opts.withDefaults()
+	m.lockOpts = &o
+}
+
+// SetLockCollection replaces name of collection for storing migrations lock.
+// By default, it is name of migrations collection with "_lock" suffix.
+func (m *Migrate) SetLockCollection(name string) {
+	m.lockCollection = name
+}
+
+func (m *Migrate) lockCollectionName() string {
+	if m.lockCollection != "" {
+		return m.lockCollection
+	}
+	return m.migrationsCollection + defaultLockCollectionSuffix
+}
+
+// lock acquires migrations lock and returns time of successful attempt which is a start of lease.
+func (m *Migrate) lock(ctx context.Context) (time.Time, error) {
+	var deadline time.Time
+	if m.lockOpts.WaitTimeout > 0 {
+		deadline = time.Now().Add(m.lockOpts.WaitTimeout)
+	}
+
+	store := m.versionStore()
+	for {
+		attempt := time.Now()
+		err := store.Lock(ctx, m.lockOpts.Owner, m.lockOpts.TTL)
+		if err == nil {
+			return attempt, nil
+		}
+		if !errors.Is(err, ErrLocked) {
+			return time.Time{}, err
+		}
+
+		if m.lockOpts.WaitTimeout == 0 || (!deadline.IsZero() && time.Now().After(deadline)) {
+			return time.Time{}, err
+		}
+
+		m.printf("Waiting for migrations lock")
+		m.logAttrs(ctx, slog.LevelInfo, "waiting for migrations lock", slog.String("owner", m.lockOpts.Owner))
+
+		timer := time.NewTimer(m.lockOpts.RetryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return time.Time{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// withLock runs fn holding migrations lock if locking is enabled.
+// Context passed to fn is cancelled if lock was taken over or lease expired without successful renewal,
+// other renewal errors (e.g. network ones) are retried on next tick.
+func (m *Migrate) withLock(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if m.lockOpts == nil {
+		return fn(ctx)
+	}
+
+	renewed, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// lock is released even if context is already done, otherwise it blocks other processes until lease expires
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), unlockTimeout)
+		defer cancel()
+
+		if unlockErr := m.versionStore().Unlock(unlockCtx, m.lockOpts.Owner); unlockErr != nil && err == nil {
+			err = unlockErr
+		}
+	}()
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(m.lockOpts.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				attempt := time.Now()
+				err := m.versionStore().RefreshLock(runCtx, m.lockOpts.Owner, m.lockOpts.TTL)
+				switch {
+				case err == nil:
+					renewed = attempt
+				case runCtx.Err() != nil:
+					// migration is already interrupted
+					return
+				case errors.Is(err, ErrLockLost):
+					cancel(err)
+					return
+				case time.Since(renewed) >= m.lockOpts.TTL:
+					cancel(fmt.Errorf("%w: lease expired: %w", ErrLockLost, err))
+					return
+				default:
+					m.printf("Failed to renew migrations lock: %v", err)
+					m.logAttrs(runCtx, slog.LevelWarn, "failed to renew migrations lock", slog.Any("error", err))
+				}
+			}
+		}
+	}()
+
+	err = fn(runCtx)
+
+	// stop lease renewal before releasing lock
+	close(stop)
+	<-stopped
+
+	if cause := context.Cause(runCtx); cause != nil && errors.Is(cause, ErrLockLost) {
+		// error of interrupted migration keeps its details (version, phase, etc.)
+		return errors.Join(cause, err)
+	}
+	return err
+}
@@ -0,0 +1,121 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestLockOptionsDefaults(t *testing.T) {
+	opts := LockOptions{}.withDefaults()
+	if opts.Owner == "" {
+		t.Errorf("Unexpected empty owner")
+	}
+	if opts.TTL != defaultLockTTL || opts.RefreshInterval != defaultLockTTL/3 || opts.RetryInterval != defaultLockRetryInterval {
+		t.Errorf("Unexpected defaults: %+v", opts)
+	}
+
+	opts = LockOptions{Owner: "me", TTL: 10 * time.Second, RefreshInterval: 20 * time.Second}.withDefaults()
+	if opts.Owner != "me" {
+		t.Errorf("Unexpected owner: %v", opts.Owner)
+	}
+	if opts.RefreshInterval >= opts.TTL {
+		t.Errorf("Unexpected refresh interval: %v", opts.RefreshInterval)
+	}
+}
+
+func TestLockCollectionName(t *testing.T) {
+	migrate := NewMigrate(nil)
+	if name := migrate.lockCollectionName(); name != "migrations_lock" {
+		t.Errorf("Unexpected lock collection: %v", name)
+	}
+	migrate.SetMigrationsCollection("history")
+	if name := migrate.lockCollectionName(); name != "history_lock" {
+		t.Errorf("Unexpected lock collection: %v", name)
+	}
+	migrate.SetLockCollection("locks")
+	if name := migrate.lockCollectionName(); name != "locks" {
+		t.Errorf("Unexpected lock collection: %v", name)
+	}
+}
+
+func TestUnlockAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &memoryStore{}
+	migrate := NewMigrate(nil, Migration{Version: 1, Up: func(ctx context.Context, db *mongo.Database) error {
+		cancel()
+		return ctx.Err()
+	}})
+	migrate.SetVersionStore(store)
+	migrate.SetLockOptions(&LockOptions{Owner: "me"})
+
+	if err := migrate.Up(ctx, AllAvailable); !errors.Is(err, context.Canceled) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if store.lockOwner != "" {
+		t.Errorf("Lock is not released")
+	}
+}
+
+// flakyRefreshStore fails first refreshes of lock with provided error.
+type flakyRefreshStore struct {
+	*memoryStore
+	failures int
+	err      error
+}
+
+func (s *flakyRefreshStore) RefreshLock(ctx context.Context, owner string, ttl time.Duration) error {
+	s.mu.Lock()
+	if s.failures > 0 {
+		s.failures--
+		s.mu.Unlock()
+		return s.err
+	}
+	s.mu.Unlock()
+	return s.memoryStore.RefreshLock(ctx, owner, ttl)
+}
+
+func TestLockRefreshTransientError(t *testing.T) {
+	store := &flakyRefreshStore{memoryStore: &memoryStore{}, failures: 2, err: errors.New("network error")}
+	migrate := NewMigrate(nil, Migration{Version: 1, Up: func(ctx context.Context, db *mongo.Database) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(250 * time.Millisecond):
+			return nil
+		}
+	}})
+	migrate.SetVersionStore(store)
+	migrate.SetLockOptions(&LockOptions{Owner: "me", TTL: 300 * time.Millisecond, RefreshInterval: 50 * time.Millisecond})
+
+	if err := migrate.Up(context.Background(), AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLockLost(t *testing.T) {
+	for _, store := range []*flakyRefreshStore{
+		{memoryStore: &memoryStore{}, failures: 1, err: ErrLockLost},
+		// lease expires while renewal fails
+		{memoryStore: &memoryStore{}, failures: 100, err: errors.New("network error")},
+	} {
+		migrate := NewMigrate(nil, Migration{Version: 1, Up: func(ctx context.Context, db *mongo.Database) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}})
+		migrate.SetVersionStore(store)
+		migrate.SetLockOptions(&LockOptions{Owner: "me", TTL: 300 * time.Millisecond, RefreshInterval: 50 * time.Millisecond})
+
+		err := migrate.Up(context.Background(), AllAvailable)
+		var migrationErr *MigrationError
+		if !errors.Is(err, ErrLockLost) || !errors.As(err, &migrationErr) || migrationErr.Version != 1 {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+}
@@ -5,26 +5,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
-	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-type collectionSpecification struct {
-	Name string `bson:"name"`
-	Type string `bson:"type"`
-}
-
-type versionRecord struct {
-	Version     uint64    `bson:"version"`
-	Description string    `bson:"description,omitempty"`
-	Timestamp   time.Time `bson:"timestamp"`
-}
-
 const defaultMigrationsCollection = "migrations"
 
+// ErrUnknownVersion returned by "To" if target version doesn't belong to any registered migration.
+var ErrUnknownVersion = errors.New("migrate: unknown migration version")
+
 // AllAvailable used in "Up" or "Down" methods to run all available migrations.
 const AllAvailable = -1
 
@@ -37,6 +29,18 @@ type Migrate struct {
 	db                   *mongo.Database
 	migrations           []Migration
 	migrationsCollection string
+	lockCollection       string
+	lockOpts             *LockOptions
+	store                VersionStore
+	transactional        bool
+	outOfOrder           OutOfOrderPolicy
+	validateChecksums    bool
+	hooks                Hooks
+	slog                 *slog.Logger
+	dirtyTracking        bool
+	migrationTimeout     time.Duration
+	operator             string
+	application          string
 	log                  Logger
 }
 
@@ -56,187 +60,328 @@ func (m *Migrate) SetMigrationsCollection(name string) {
 	m.migrationsCollection = name
 }
 
-func (m *Migrate) isCollectionExist(ctx context.Context, name string) (isExist bool, err error) {
-	collections, err := m.getCollections(ctx)
+// Version returns current database version and comment.
+func (m *Migrate) Version(ctx context.Context) (uint64, string, error) {
+	state, err := m.state(ctx)
 	if err != nil {
-		return false, err
+		return 0, "", err
 	}
 
-	for _, c := range collections {
-		if name == c.Name {
-			return true, nil
-		}
-	}
-	return false, nil
+	return state.Version, state.Description, nil
 }
 
-func (m *Migrate) createCollectionIfNotExist(ctx context.Context, name string) error {
-	exist, err := m.isCollectionExist(ctx, name)
+func (m *Migrate) state(ctx context.Context) (versionState, error) {
+	rec, err := m.versionStore().Current(ctx)
 	if err != nil {
-		return err
-	}
-	if exist {
-		return nil
+		return versionState{}, err
 	}
-
-	command := bson.D{bson.E{Key: "create", Value: name}}
-	if err = m.db.RunCommand(ctx, command).Err(); err != nil {
-		return err
-	}
-
-	return nil
+	return stateFromRecord(rec), nil
 }
 
-func (m *Migrate) getCollections(ctx context.Context) (collections []collectionSpecification, err error) {
-	cursor, err := m.db.ListCollections(ctx, bson.D{})
-	if err != nil {
-		return nil, err
-	}
+// SetVersion forcibly changes database version to provided one.
+// All migrations with versions less or equal than provided one considered applied after this.
+// Dirty state is cleared too.
+func (m *Migrate) SetVersion(ctx context.Context, version uint64, description string) error {
+	return m.insertRecord(ctx, VersionRecord{
+		Version:     version,
+		Description: description,
+		Event:       HistoryForced,
+	})
+}
 
-	if cursor != nil {
-		defer func(cursor *mongo.Cursor) {
-			curErr := cursor.Close(ctx)
-			if curErr != nil {
-				if err != nil {
-					err = fmt.Errorf("migrate: get collection failed: %w", err)
-				} else {
-					err = curErr
-				}
-			}
-		}(cursor)
-	}
+func (m *Migrate) insertRecord(ctx context.Context, rec VersionRecord) error {
+	rec.Timestamp = time.Now().UTC()
+	m.fillAudit(&rec)
+	return m.versionStore().Record(ctx, rec)
+}
 
-	for cursor.Next(ctx) {
-		var collection collectionSpecification
+// SetOutOfOrderPolicy sets policy of handling not applied migrations with versions lower than current database version.
+// By default, such migrations are ignored (OutOfOrderIgnore).
+func (m *Migrate) SetOutOfOrderPolicy(policy OutOfOrderPolicy) {
+	m.outOfOrder = policy
+}
 
-		err := cursor.Decode(&collection)
+// Up performs "up" migrations to latest available version.
+// If n<=0 all "up" migrations with newer versions will be performed.
+// If n>0 only n migrations with newer version will be performed.
+// If locking is enabled migrations lock is held while migrations are performed.
+func (m *Migrate) Up(ctx context.Context, n int) error {
+	return m.withLock(ctx, func(ctx context.Context) error {
+		state, err := m.state(ctx)
 		if err != nil {
-			return nil, err
+			return err
 		}
-
-		if len(collection.Type) == 0 || collection.Type == "collection" {
-			collections = append(collections, collection)
+		steps, err := m.planUp(state, n)
+		if err != nil {
+			return err
 		}
-	}
-
-	if err := cursor.Err(); err != nil {
-		return nil, err
-	}
-
-	return
+		return m.execute(ctx, state, steps)
+	})
 }
 
-// Version returns current database version and comment.
-func (m *Migrate) Version(ctx context.Context) (uint64, string, error) {
-	if err := m.createCollectionIfNotExist(ctx, m.migrationsCollection); err != nil {
-		return 0, "", err
+// upMigrations selects migrations with versions up to target to be applied by "up" in order of applying.
+func (m *Migrate) upMigrations(state versionState, n int, target uint64) ([]Migration, error) {
+	if n <= 0 || n > len(m.migrations) {
+		n = len(m.migrations)
 	}
+	migrationSort(m.migrations)
 
-	filter := bson.D{{}}
-	sort := bson.D{bson.E{Key: "_id", Value: -1}}
-	opts := options.FindOne().SetSort(sort)
-
-	// find record with the greatest id (assuming it`s latest also)
-	result := m.db.Collection(m.migrationsCollection).FindOne(ctx, filter, opts)
-	err := result.Err()
-	switch {
-	case errors.Is(err, mongo.ErrNoDocuments):
-		return 0, "", nil
-	case err != nil:
-		return 0, "", err
+	var (
+		ret  []Migration
+		gaps []uint64
+	)
+	for _, migration := range m.migrations {
+		if migration.Version > target {
+			break
+		}
+		if state.isApplied(migration.Version) || migration.Up == nil {
+			continue
+		}
+		if migration.Version <= state.Version {
+			switch m.outOfOrder {
+			case OutOfOrderIgnore:
+				continue
+			case OutOfOrderFail:
+				gaps = append(gaps, migration.Version)
+				continue
+			}
+		}
+		if len(ret) < n {
+			ret = append(ret, migration)
+		}
 	}
-
-	var rec versionRecord
-	if err := result.Decode(&rec); err != nil {
-		return 0, "", err
+	if len(gaps) > 0 {
+		return nil, &GapsError{Versions: gaps}
 	}
-
-	return rec.Version, rec.Description, nil
+	return ret, nil
 }
 
-// SetVersion forcibly changes database version to provided one.
-func (m *Migrate) SetVersion(ctx context.Context, version uint64, description string) error {
-	rec := versionRecord{
-		Version:     version,
-		Timestamp:   time.Now().UTC(),
-		Description: description,
-	}
+// Down performs "down" migration to the oldest available version.
+// If n<=0 all "down" migrations with older version will be performed.
+// If n>0 only n migrations with older version will be performed.
+// If locking is enabled migrations lock is held while migrations are performed.
+func (m *Migrate) Down(ctx context.Context, n int) error {
+	return m.withLock(ctx, func(ctx context.Context) error {
+		state, err := m.state(ctx)
+		if err != nil {
+			return err
+		}
+		steps, err := m.planDown(state, n)
+		if err != nil {
+			return err
+		}
+		return m.execute(ctx, state, steps)
+	})
+}
 
-	_, err := m.db.Collection(m.migrationsCollection).InsertOne(ctx, rec)
-	if err != nil {
-		return err
+// isApplied reports whether migration with provided version is applied according to out-of-order policy.
+func (m *Migrate) isApplied(state versionState, version uint64) bool {
+	if m.outOfOrder == OutOfOrderIgnore {
+		// database version is a watermark in this mode
+		return version != 0 && version <= state.Version
 	}
-
-	return nil
+	return state.isApplied(version)
 }
 
-// Up performs "up" migrations to latest available version.
-// If n<=0 all "up" migrations with newer versions will be performed.
-// If n>0 only n migrations with newer version will be performed.
-func (m *Migrate) Up(ctx context.Context, n int) error {
-	currentVersion, _, err := m.Version(ctx)
-	if err != nil {
-		return err
-	}
+// downMigrations selects migrations with versions newer than target to be reverted by "down" in order of reverting.
+func (m *Migrate) downMigrations(state versionState, n int, target uint64) []Migration {
 	if n <= 0 || n > len(m.migrations) {
 		n = len(m.migrations)
 	}
 	migrationSort(m.migrations)
 
-	for i, p := 0, 0; i < len(m.migrations) && p < n; i++ {
+	var ret []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(ret) < n; i-- {
 		migration := m.migrations[i]
-		if migration.Version <= currentVersion || migration.Up == nil {
+		if migration.Version <= target {
+			break
+		}
+		if !m.isApplied(state, migration.Version) || migration.Down == nil {
 			continue
 		}
-		p++
-		if err := migration.Up(ctx, m.db); err != nil {
+		ret = append(ret, migration)
+	}
+	return ret
+}
+
+// To migrates database up or down to provided version.
+// Target version must be either 0 (revert all migrations) or version of registered migration.
+// Applied migrations with newer versions are reverted, not applied migrations with versions up to target are applied.
+// If locking is enabled migrations lock is held while migrations are performed.
+func (m *Migrate) To(ctx context.Context, target uint64) error {
+	if err := m.checkTarget(target); err != nil {
+		return err
+	}
+
+	return m.withLock(ctx, func(ctx context.Context) error {
+		state, err := m.state(ctx)
+		if err != nil {
 			return err
 		}
-		if err := m.SetVersion(ctx, migration.Version, migration.Description); err != nil {
+		steps, err := m.planTo(state, target)
+		if err != nil {
 			return err
 		}
+		return m.execute(ctx, state, steps)
+	})
+}
 
-		m.printUp(migration.Version, migration.Description)
+func (m *Migrate) checkTarget(target uint64) error {
+	if target != 0 && !hasVersion(m.migrations, target) {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, target)
 	}
 	return nil
 }
 
-// Down performs "down" migration to the oldest available version.
-// If n<=0 all "down" migrations with older version will be performed.
-// If n>0 only n migrations with older version will be performed.
-func (m *Migrate) Down(ctx context.Context, n int) error {
-	currentVersion, _, err := m.Version(ctx)
-	if err != nil {
+// checkReversible checks that all applied migrations newer than target have "down" function.
+func (m *Migrate) checkReversible(state versionState, target uint64) error {
+	for _, migration := range m.migrations {
+		if migration.Version > target && m.isApplied(state, migration.Version) && migration.Down == nil {
+			return fmt.Errorf("migrate: migration %d has no down function, can not migrate to version %d", migration.Version, target)
+		}
+	}
+	return nil
+}
+
+// execute performs planned steps one by one.
+func (m *Migrate) execute(ctx context.Context, state versionState, steps []step) (err error) {
+	hooks := m.getHooks()
+	plan := newPlan(state, steps)
+	if err := hooks.BeforeRun(ctx, plan); err != nil {
 		return err
 	}
-	if n <= 0 || n > len(m.migrations) {
-		n = len(m.migrations)
+	defer func() {
+		hooks.AfterRun(ctx, plan, err)
+	}()
+
+	m.logSkipped(ctx, state, steps)
+	if len(steps) > 0 {
+		m.logAttrs(ctx, slog.LevelInfo, "running migrations",
+			slog.Int("count", len(steps)),
+			slog.Uint64("from_version", plan.FromVersion),
+			slog.Uint64("to_version", plan.ToVersion),
+		)
 	}
-	migrationSort(m.migrations)
 
-	for i, p := len(m.migrations)-1, 0; i >= 0 && p < n; i-- {
-		migration := m.migrations[i]
-		if migration.Version > currentVersion || migration.Down == nil {
-			continue
+	lastVersion := state.Version
+	for _, s := range steps {
+		fn := s.migration.Up
+		if s.direction == DirectionDown {
+			fn = s.migration.Down
 		}
-		p++
-		if err := migration.Down(ctx, m.db); err != nil {
+
+		event := MigrationEvent{Migration: s.migration, Direction: s.direction, Version: s.record.Version}
+		if err := hooks.BeforeMigration(ctx, event); err != nil {
 			return err
 		}
 
-		var prevMigration Migration
-		if i == 0 {
-			prevMigration = Migration{Version: 0}
+		start := time.Now()
+		var (
+			phase MigrationPhase
+			err   error
+		)
+		if m.dirtyTracking && !m.isTransactional(s.migration) {
+			phase, err = PhaseRecord, m.markDirty(ctx, state, s)
+		}
+		if err == nil {
+			phase, err = m.runMigration(ctx, s.migration, fn, s.auditRecord())
+		}
+		event.Duration = time.Since(start)
+		if err != nil {
+			m.recordFailure(ctx, state, s, event.Duration, err)
+			m.logAttrs(ctx, slog.LevelError, "migration failed", append(migrationAttrs(event),
+				slog.Duration("duration", event.Duration),
+				slog.String("phase", string(phase)),
+				slog.Any("error", err),
+			)...)
+			err = &MigrationError{
+				Version:     s.migration.Version,
+				Description: s.migration.Description,
+				Direction:   s.direction,
+				Phase:       phase,
+				LastVersion: lastVersion,
+				Err:         err,
+			}
+			hooks.OnError(ctx, event, err)
+			return err
+		}
+		m.logAttrs(ctx, slog.LevelInfo, "migration performed", append(migrationAttrs(event),
+			slog.Duration("duration", event.Duration),
+			slog.Uint64("result_version", event.Version),
+		)...)
+		hooks.AfterMigration(ctx, event)
+		lastVersion = s.record.Version
+		state = stateFromRecord(s.record)
+
+		if s.direction == DirectionDown {
+			m.printDown(s.migration.Version, s.migration.Description)
 		} else {
-			prevMigration = m.migrations[i-1]
+			m.printUp(s.migration.Version, s.migration.Description)
 		}
-		if err := m.SetVersion(ctx, prevMigration.Version, prevMigration.Description); err != nil {
+	}
+	return nil
+}
+
+// runMigration calls migration function and records resulting version.
+// For transactional migrations both actions are performed in one transaction if version store allows it.
+// Phase in which error occurred is returned with it.
+func (m *Migrate) runMigration(ctx context.Context, migration Migration, fn MigrationFunc, rec VersionRecord) (MigrationPhase, error) {
+	phase := PhaseTransaction
+	call := func(ctx context.Context) error {
+		phase = PhaseRun
+		start := time.Now()
+		var data bson.D
+		if err := m.callWithTimeout(m.withMigrationContext(ctx, migration, rec.Direction, &data), migration, fn); err != nil {
 			return err
 		}
+		rec.DurationMS = time.Since(start).Milliseconds()
+		rec.Data = nil
+		if len(data) > 0 {
+			raw, err := bson.Marshal(data)
+			if err != nil {
+				return fmt.Errorf("migrate: marshal migration data: %w", err)
+			}
+			rec.Data = raw
+		}
+		return nil
+	}
+	record := func(ctx context.Context) error {
+		phase = PhaseRecord
+		return m.insertRecord(ctx, rec)
+	}
 
-		m.printDown(migration.Version, migration.Description)
+	switch {
+	case !m.isTransactional(migration):
+		if err := call(ctx); err != nil {
+			return phase, err
+		}
+		return phase, record(ctx)
+	case m.recordInTransaction():
+		return phase, m.withTransaction(ctx, func(ctx context.Context) error {
+			if err := call(ctx); err != nil {
+				return err
+			}
+			if err := record(ctx); err != nil {
+				return err
+			}
+			// further errors are caused by transaction commit
+			phase = PhaseTransaction
+			return nil
+		})
+	default:
+		err := m.withTransaction(ctx, func(ctx context.Context) error {
+			if err := call(ctx); err != nil {
+				return err
+			}
+			phase = PhaseTransaction
+			return nil
+		})
+		if err != nil {
+			return phase, err
+		}
+		// store is not bound to session, so record is written after commit
+		return phase, record(ctx)
 	}
-	return nil
 }
 
 // SetLogger sets a logger to print the migration process
@@ -0,0 +1,119 @@
+// Package migratetest allows to unit-test migrations ordering and error handling without MongoDB.
+//
+// Harness runs "Up", "Down" and "To" of migrate.Migrate against in-memory version store
+// with migration functions replaced by stubs recording calls:
+//
+//	h := migratetest.New(migrate.RegisteredMigrations()...)
+//	if err := h.Migrate.Up(ctx, 2); err != nil {
+//		t.Fatal(err)
+//	}
+//	if calls := h.Calls(); len(calls) != 2 {
+//		t.Errorf("unexpected calls: %v", calls)
+//	}
+//
+// VerifyRoundTrip and CheckRoundTrip check against real database that "down" functions revert "up" ones.
+package migratetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Call describes single invocation of migration function.
+type Call struct {
+	Version     uint64
+	Description string
+	Direction   migrate.Direction
+}
+
+func (c Call) String() string {
+	return fmt.Sprintf("%s %d", c.Direction, c.Version)
+}
+
+type failure struct {
+	version   uint64
+	direction migrate.Direction
+}
+
+// Harness performs migrations without database recording invoked migration functions.
+type Harness struct {
+	// Migrate performs migrations. It may be configured as usual (out-of-order policy, hooks, dirty tracking, etc.)
+	// except transactions and version store which require database.
+	Migrate *migrate.Migrate
+	// Store keeps migrations history.
+	Store *Store
+
+	mu       sync.Mutex
+	calls    []Call
+	failures map[failure]error
+}
+
+// New creates harness for provided migrations. Their "Up" and "Down" functions are not called,
+// stubs recording calls are used instead (nil "Down" stays nil, so irreversible migrations are handled as usual).
+// Transactional flag of migrations is reset, because transactions require database.
+func New(migrations ...migrate.Migration) *Harness {
+	h := &Harness{Store: NewStore(), failures: make(map[failure]error)}
+
+	stubs := make([]migrate.Migration, len(migrations))
+	for i, migration := range migrations {
+		migration.Transactional = false
+		if migration.Up != nil {
+			migration.Up = h.stub(migration, migrate.DirectionUp)
+		}
+		if migration.Down != nil {
+			migration.Down = h.stub(migration, migrate.DirectionDown)
+		}
+		stubs[i] = migration
+	}
+
+	h.Migrate = migrate.NewMigrate(nil, stubs...)
+	h.Migrate.SetVersionStore(h.Store)
+	return h
+}
+
+func (h *Harness) stub(migration migrate.Migration, direction migrate.Direction) migrate.MigrationFunc {
+	return func(ctx context.Context, db *mongo.Database) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		h.calls = append(h.calls, Call{Version: migration.Version, Description: migration.Description, Direction: direction})
+		if err := h.failures[failure{version: migration.Version, direction: direction}]; err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
+}
+
+// FailOn makes migration function with provided version and direction return err when called.
+// Passing nil err removes failure.
+func (h *Harness) FailOn(version uint64, direction migrate.Direction, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := failure{version: version, direction: direction}
+	if err == nil {
+		delete(h.failures, key)
+		return
+	}
+	h.failures[key] = err
+}
+
+// Calls returns invoked migration functions in order of invocation.
+func (h *Harness) Calls() []Call {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]Call(nil), h.calls...)
+}
+
+// Reset forgets recorded calls. History in store is kept.
+func (h *Harness) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.calls = nil
+}
@@ -0,0 +1,126 @@
+package migratetest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func noop(ctx context.Context, db *mongo.Database) error {
+	return nil
+}
+
+func testHarness() *Harness {
+	return New(
+		migrate.Migration{Version: 3, Description: "three", Up: noop, Down: noop},
+		migrate.Migration{Version: 1, Description: "one", Up: noop, Down: noop},
+		migrate.Migration{Version: 2, Description: "two", Up: noop, Down: noop, Transactional: true},
+	)
+}
+
+func TestHarnessOrder(t *testing.T) {
+	ctx := context.Background()
+	h := testHarness()
+
+	if err := h.Migrate.Up(ctx, 2); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := h.Migrate.Up(ctx, migrate.AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := h.Migrate.Down(ctx, 2); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	expected := []Call{
+		{Version: 1, Description: "one", Direction: migrate.DirectionUp},
+		{Version: 2, Description: "two", Direction: migrate.DirectionUp},
+		{Version: 3, Description: "three", Direction: migrate.DirectionUp},
+		{Version: 3, Description: "three", Direction: migrate.DirectionDown},
+		{Version: 2, Description: "two", Direction: migrate.DirectionDown},
+	}
+	if calls := h.Calls(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Unexpected calls: %v", calls)
+		return
+	}
+
+	version, _, err := h.Migrate.Version(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if version != 1 {
+		t.Errorf("Unexpected version: %v", version)
+	}
+}
+
+func TestHarnessFailure(t *testing.T) {
+	expectedErr := errors.New("normal error")
+	ctx := context.Background()
+	h := testHarness()
+	h.FailOn(2, migrate.DirectionUp, expectedErr)
+
+	err := h.Migrate.Up(ctx, migrate.AllAvailable)
+	var migrationErr *migrate.MigrationError
+	if !errors.As(err, &migrationErr) || !errors.Is(err, expectedErr) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if migrationErr.Version != 2 || migrationErr.LastVersion != 1 {
+		t.Errorf("Unexpected error: %+v", migrationErr)
+	}
+
+	failed, err := h.Store.History(ctx, migrate.HistoryFilter{Events: []migrate.HistoryEvent{migrate.HistoryFailed}})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(failed) != 1 || failed[0].MigrationVersion != 2 {
+		t.Errorf("Unexpected records: %+v", failed)
+		return
+	}
+
+	h.FailOn(2, migrate.DirectionUp, nil)
+	h.Reset()
+	if err := h.Migrate.Up(ctx, migrate.AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if calls := h.Calls(); len(calls) != 2 || calls[0].Version != 2 || calls[1].Version != 3 {
+		t.Errorf("Unexpected calls: %v", calls)
+	}
+}
+
+func TestStoreLock(t *testing.T) {
+	ctx := context.Background()
+	h := testHarness()
+	h.Migrate.SetLockOptions(&migrate.LockOptions{Owner: "me"})
+
+	if err := h.Store.Lock(ctx, "other", time.Minute); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := h.Migrate.Up(ctx, migrate.AllAvailable); !errors.Is(err, migrate.ErrLocked) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := h.Store.Unlock(ctx, "other"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := h.Migrate.Up(ctx, migrate.AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if owner := h.Store.LockOwner(); owner != "" {
+		t.Errorf("Unexpected lock owner: %v", owner)
+	}
+}
@@ -0,0 +1,315 @@
+package migratetest
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Snapshot describes database schema: collections and views with their options and indexes.
+// Documents are not included.
+type Snapshot struct {
+	// Collections maps collection or view name to its schema. System collections are skipped.
+	Collections map[string]CollectionSnapshot
+}
+
+// CollectionSnapshot describes schema of single collection or view.
+type CollectionSnapshot struct {
+	// Type is "collection" or "view".
+	Type string
+	// Options contains collection options (validator, validation level and action, view pipeline, etc.)
+	// in canonical Extended JSON. Validation settings equal to server defaults (empty validator, "strict" level
+	// and "error" action) are omitted because server keeps them once validator was set, even if it was removed later.
+	Options string
+	// Indexes maps index name to its specification in canonical Extended JSON.
+	Indexes map[string]string
+}
+
+type collectionInfo struct {
+	Name    string   `bson:"name"`
+	Type    string   `bson:"type"`
+	Options bson.Raw `bson:"options"`
+}
+
+// TakeSnapshot reads schema of database.
+func TakeSnapshot(ctx context.Context, db *mongo.Database) (*Snapshot, error) {
+	cursor, err := db.ListCollections(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []collectionInfo
+	if err := cursor.All(ctx, &infos); err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{Collections: make(map[string]CollectionSnapshot, len(infos))}
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name, "system.") {
+			continue
+		}
+
+		cs := CollectionSnapshot{Type: info.Type, Indexes: make(map[string]string)}
+		if info.Options != nil {
+			if cs.Options, err = collectionOptions(info.Options); err != nil {
+				return nil, fmt.Errorf("options of collection %q: %w", info.Name, err)
+			}
+		}
+		if info.Type == "view" {
+			snapshot.Collections[info.Name] = cs
+			continue
+		}
+
+		if cs.Indexes, err = indexSpecs(ctx, db.Collection(info.Name)); err != nil {
+			return nil, fmt.Errorf("indexes of collection %q: %w", info.Name, err)
+		}
+		snapshot.Collections[info.Name] = cs
+	}
+	return snapshot, nil
+}
+
+// collectionOptions returns collection options in canonical Extended JSON without default validation settings.
+func collectionOptions(raw bson.Raw) (string, error) {
+	var options bson.D
+	if err := bson.Unmarshal(raw, &options); err != nil {
+		return "", err
+	}
+
+	options = slices.DeleteFunc(options, func(e bson.E) bool {
+		switch e.Key {
+		case "validator":
+			validator, ok := e.Value.(bson.D)
+			return ok && len(validator) == 0
+		case "validationLevel":
+			return e.Value == "strict"
+		case "validationAction":
+			return e.Value == "error"
+		}
+		return false
+	})
+
+	content, err := bson.MarshalExtJSON(options, true, false)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+func indexSpecs(ctx context.Context, collection *mongo.Collection) (map[string]string, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []bson.D
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]string, len(indexes))
+	for _, index := range indexes {
+		var name string
+		// namespace is reported by older servers and doesn't describe index itself
+		spec := slices.DeleteFunc(index, func(e bson.E) bool {
+			if e.Key == "name" {
+				name, _ = e.Value.(string)
+			}
+			return e.Key == "ns"
+		})
+		content, err := bson.MarshalExtJSON(spec, true, false)
+		if err != nil {
+			return nil, err
+		}
+		ret[name] = string(content)
+	}
+	return ret, nil
+}
+
+// Diff returns human-readable differences of other snapshot from s. Empty result means that schemas are equal.
+func (s *Snapshot) Diff(other *Snapshot) []string {
+	var diff []string
+	for _, name := range sortedKeys(s.Collections) {
+		cs := s.Collections[name]
+		ocs, ok := other.Collections[name]
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("%s %q is missing", cs.Type, name))
+			continue
+		case cs.Type != ocs.Type:
+			diff = append(diff, fmt.Sprintf("%q type differs: %s != %s", name, cs.Type, ocs.Type))
+			continue
+		case cs.Options != ocs.Options:
+			diff = append(diff, fmt.Sprintf("%s %q options differ: %s != %s", cs.Type, name, cs.Options, ocs.Options))
+		}
+
+		for _, index := range sortedKeys(cs.Indexes) {
+			spec, ok := ocs.Indexes[index]
+			switch {
+			case !ok:
+				diff = append(diff, fmt.Sprintf("index %q of collection %q is missing", index, name))
+			case spec != cs.Indexes[index]:
+				diff = append(diff, fmt.Sprintf("index %q of collection %q differs: %s != %s", index, name, cs.Indexes[index], spec))
+			}
+		}
+		for _, index := range sortedKeys(ocs.Indexes) {
+			if _, ok := cs.Indexes[index]; !ok {
+				diff = append(diff, fmt.Sprintf("index %q of collection %q is unexpected", index, name))
+			}
+		}
+	}
+	for _, name := range sortedKeys(other.Collections) {
+		if _, ok := s.Collections[name]; !ok {
+			diff = append(diff, fmt.Sprintf("%s %q is unexpected", other.Collections[name].Type, name))
+		}
+	}
+	return diff
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// RoundTripResult describes round trip check of single migration.
+type RoundTripResult struct {
+	Version     uint64
+	Description string
+	// MissingDown is set if migration has no "down" function.
+	MissingDown bool
+	// DownDiff contains differences of schema after "down" from schema before "up".
+	DownDiff []string
+	// ReapplyDiff contains differences of schema after repeated "up" from schema after first one.
+	ReapplyDiff []string
+}
+
+// OK reports whether migration passed round trip check.
+func (r RoundTripResult) OK() bool {
+	return !r.MissingDown && len(r.DownDiff) == 0 && len(r.ReapplyDiff) == 0
+}
+
+// RoundTripReport contains results of round trip check for each migration in order of applying.
+type RoundTripReport struct {
+	Results []RoundTripResult
+}
+
+// Failed returns results of migrations which didn't pass round trip check.
+func (r *RoundTripReport) Failed() []RoundTripResult {
+	var ret []RoundTripResult
+	for _, result := range r.Results {
+		if !result.OK() {
+			ret = append(ret, result)
+		}
+	}
+	return ret
+}
+
+// VerifyRoundTrip checks that "down" function of each migration reverts schema changes made by "up".
+// Migrations are processed in version order: each one is applied, reverted and applied again,
+// schema (collections, views, their options including validators and indexes) is compared after each step.
+// If "down" of migration didn't revert schema, it is not applied again and check stops after it,
+// because following migrations would run against wrong schema, so report contains results up to this migration.
+// Each migration is performed by separate migrate.Migrate keeping history in memory (see Store),
+// so helpers storing data in history (e.g. migrate.IndexChanges) work, but database history is not read or written.
+// Use empty dedicated database. Error is returned if migration function fails.
+func VerifyRoundTrip(ctx context.Context, db *mongo.Database, migrations ...migrate.Migration) (*RoundTripReport, error) {
+	migrations = slices.Clone(migrations)
+	slices.SortFunc(migrations, func(a, b migrate.Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	report := &RoundTripReport{}
+	for _, migration := range migrations {
+		if migration.Up == nil {
+			continue
+		}
+		result, err := roundTrip(ctx, db, migration)
+		if err != nil {
+			return report, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+		report.Results = append(report.Results, result)
+		if len(result.DownDiff) > 0 {
+			break
+		}
+	}
+	return report, nil
+}
+
+func roundTrip(ctx context.Context, db *mongo.Database, migration migrate.Migration) (RoundTripResult, error) {
+	result := RoundTripResult{Version: migration.Version, Description: migration.Description}
+
+	runner := migrate.NewMigrate(db, migration)
+	runner.SetVersionStore(NewStore())
+
+	before, err := TakeSnapshot(ctx, db)
+	if err != nil {
+		return result, err
+	}
+	if err := runner.Up(ctx, migrate.AllAvailable); err != nil {
+		return result, fmt.Errorf("up: %w", err)
+	}
+	if migration.Down == nil {
+		result.MissingDown = true
+		return result, nil
+	}
+
+	applied, err := TakeSnapshot(ctx, db)
+	if err != nil {
+		return result, err
+	}
+	if err := runner.Down(ctx, migrate.AllAvailable); err != nil {
+		return result, fmt.Errorf("down: %w", err)
+	}
+	reverted, err := TakeSnapshot(ctx, db)
+	if err != nil {
+		return result, err
+	}
+	result.DownDiff = before.Diff(reverted)
+	if len(result.DownDiff) > 0 {
+		// "up" may fail on partially reverted schema, so it is not repeated
+		return result, nil
+	}
+
+	if err := runner.Up(ctx, migrate.AllAvailable); err != nil {
+		return result, fmt.Errorf("repeated up: %w", err)
+	}
+	reapplied, err := TakeSnapshot(ctx, db)
+	if err != nil {
+		return result, err
+	}
+	result.ReapplyDiff = applied.Diff(reapplied)
+
+	return result, nil
+}
+
+// CheckRoundTrip runs VerifyRoundTrip and reports error for each migration which didn't pass check.
+func CheckRoundTrip(t testing.TB, ctx context.Context, db *mongo.Database, migrations ...migrate.Migration) {
+	t.Helper()
+
+	report, err := VerifyRoundTrip(ctx, db, migrations...)
+	if err != nil {
+		t.Errorf("Round trip failed: %v", err)
+		return
+	}
+	for _, result := range report.Failed() {
+		if result.MissingDown {
+			t.Errorf("Migration %d (%s) has no down function", result.Version, result.Description)
+		}
+		if len(result.DownDiff) > 0 {
+			t.Errorf("Migration %d (%s) down doesn't revert up:\n%s", result.Version, result.Description, strings.Join(result.DownDiff, "\n"))
+		}
+		if len(result.ReapplyDiff) > 0 {
+			t.Errorf("Migration %d (%s) repeated up differs from first one:\n%s", result.Version, result.Description, strings.Join(result.ReapplyDiff, "\n"))
+		}
+	}
+}
@@ -0,0 +1,145 @@
+//go:build integration
+
+package migratetest
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var db *mongo.Database
+
+func TestMain(m *testing.M) {
+	addr, err := url.Parse(os.Getenv("MONGO_URL"))
+	if err != nil {
+		panic(err)
+	}
+	opt := options.Client().ApplyURI(addr.String())
+	client, err := mongo.Connect(opt)
+	if err != nil {
+		panic(err)
+	}
+	db = client.Database(strings.TrimLeft(addr.Path, "/") + "_migratetest")
+	defer db.Drop(context.Background())
+	os.Exit(m.Run())
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	defer db.Drop(ctx)
+
+	index := mongo.IndexModel{Keys: bson.D{{Key: "a", Value: 1}}, Options: options.Index().SetName("a")}
+	report, err := VerifyRoundTrip(ctx, db,
+		migrate.Migration{Version: 1, Description: "symmetric", Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("test").Indexes().CreateOne(ctx, index)
+			return err
+		}, Down: func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection("test").Indexes().DropOne(ctx, "a")
+		}},
+		migrate.Migration{Version: 2, Description: "irreversible", Up: func(ctx context.Context, db *mongo.Database) error {
+			return nil
+		}},
+		migrate.Migration{Version: 3, Description: "asymmetric", Up: func(ctx context.Context, db *mongo.Database) error {
+			return db.CreateCollection(ctx, "other")
+		}, Down: func(ctx context.Context, db *mongo.Database) error {
+			return nil
+		}},
+		migrate.Migration{Version: 4, Description: "not checked", Up: func(ctx context.Context, db *mongo.Database) error {
+			return errors.New("must not be called after asymmetric migration")
+		}},
+	)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	failed := report.Failed()
+	if len(report.Results) != 3 || len(failed) != 2 {
+		t.Errorf("Unexpected report: %+v", report)
+		return
+	}
+	if failed[0].Version != 2 || !failed[0].MissingDown {
+		t.Errorf("Unexpected result: %+v", failed[0])
+	}
+	if failed[1].Version != 3 || len(failed[1].DownDiff) != 1 {
+		t.Errorf("Unexpected result: %+v", failed[1])
+	}
+}
+
+func TestCheckRoundTripIndexMigration(t *testing.T) {
+	ctx := context.Background()
+	defer db.Drop(ctx)
+
+	if _, err := db.Collection("test").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "b", Value: 1}},
+		Options: options.Index().SetName("b").SetUnique(true),
+	}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	CheckRoundTrip(t, ctx, db, migrate.IndexMigration(1, "indexes",
+		migrate.CreateIndex("test", mongo.IndexModel{
+			Keys:    bson.D{{Key: "a", Value: 1}},
+			Options: options.Index().SetName("a").SetExpireAfterSeconds(3600),
+		}),
+		migrate.DropIndex("test", "b"),
+	))
+}
+
+func TestCheckRoundTripValidatorMigration(t *testing.T) {
+	ctx := context.Background()
+	defer db.Drop(ctx)
+
+	if err := db.CreateCollection(ctx, "existing", options.CreateCollection().SetValidator(
+		migrate.JSONSchema(bson.D{{Key: "bsonType", Value: "object"}, {Key: "required", Value: bson.A{"name"}}}),
+	)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := db.CreateCollection(ctx, "plain"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	CheckRoundTrip(t, ctx, db,
+		// collection is created by up and dropped by down
+		migrate.ValidatorMigration(1, "new collection", "created", migrate.Validator{
+			Validator: migrate.JSONSchema(bson.D{{Key: "bsonType", Value: "object"}}),
+		}),
+		// only level is changed, schema is kept
+		migrate.ValidatorMigration(2, "level", "existing", migrate.Validator{Level: "moderate"}),
+		// the first validator of collection, server keeps default settings after revert
+		migrate.ValidatorMigration(3, "first validator", "plain", migrate.Validator{
+			Validator: migrate.JSONSchema(bson.D{{Key: "bsonType", Value: "object"}}),
+			Level:     "moderate",
+			Action:    "warn",
+		}),
+	)
+
+	// second migration is applied after check
+	var specs []struct {
+		Options bson.M `bson:"options"`
+	}
+	cursor, err := db.ListCollections(ctx, bson.D{{Key: "name", Value: "existing"}})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := cursor.All(ctx, &specs); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(specs) != 1 || specs[0].Options["validator"] == nil || specs[0].Options["validationLevel"] != "moderate" {
+		t.Errorf("Unexpected collection specs: %+v", specs)
+	}
+}
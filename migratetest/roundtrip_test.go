@@ -0,0 +1,83 @@
+package migratetest
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSnapshotDiff(t *testing.T) {
+	before := &Snapshot{Collections: map[string]CollectionSnapshot{
+		"a": {Type: "collection", Options: `{}`, Indexes: map[string]string{"_id_": `{"key":{"_id":1}}`, "x": `{"key":{"x":1}}`}},
+		"b": {Type: "collection", Options: `{}`},
+	}}
+	after := &Snapshot{Collections: map[string]CollectionSnapshot{
+		"a": {Type: "collection", Options: `{"validator":{}}`, Indexes: map[string]string{"_id_": `{"key":{"_id":1}}`, "y": `{"key":{"y":1}}`}},
+		"c": {Type: "view", Options: `{"viewOn":"a"}`},
+	}}
+
+	if diff := before.Diff(before); len(diff) != 0 {
+		t.Errorf("Unexpected diff: %v", diff)
+	}
+
+	expected := []string{
+		`collection "a" options differ: {} != {"validator":{}}`,
+		`index "x" of collection "a" is missing`,
+		`index "y" of collection "a" is unexpected`,
+		`collection "b" is missing`,
+		`view "c" is unexpected`,
+	}
+	if diff := before.Diff(after); !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Unexpected diff: %q", diff)
+	}
+}
+
+func TestRoundTripResultOK(t *testing.T) {
+	report := &RoundTripReport{Results: []RoundTripResult{
+		{Version: 1},
+		{Version: 2, MissingDown: true},
+		{Version: 3, DownDiff: []string{"difference"}},
+	}}
+	if failed := report.Failed(); len(failed) != 2 || failed[0].Version != 2 || failed[1].Version != 3 {
+		t.Errorf("Unexpected failed results: %+v", failed)
+	}
+}
+
+func TestCollectionOptions(t *testing.T) {
+	for _, tc := range []struct {
+		options  bson.D
+		expected string
+	}{
+		{
+			options: bson.D{
+				{Key: "validator", Value: bson.D{}},
+				{Key: "validationLevel", Value: "strict"},
+				{Key: "validationAction", Value: "error"},
+			},
+			expected: `{}`,
+		},
+		{
+			options: bson.D{
+				{Key: "validator", Value: bson.D{{Key: "a", Value: bson.D{{Key: "$exists", Value: true}}}}},
+				{Key: "validationLevel", Value: "moderate"},
+				{Key: "validationAction", Value: "error"},
+			},
+			expected: `{"validator":{"a":{"$exists":true}},"validationLevel":"moderate"}`,
+		},
+	} {
+		raw, err := bson.Marshal(tc.options)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		options, err := collectionOptions(raw)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		if options != tc.expected {
+			t.Errorf("Unexpected options: %s", options)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package migratetest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	migrate "github.com/xakep666/mongo-migrate"
+)
+
+// Store is a migrate.VersionStore keeping history and lock in memory.
+// It is safe for concurrent use.
+type Store struct {
+	mu             sync.Mutex
+	records        []migrate.VersionRecord
+	lockOwner      string
+	lockExpiration time.Time
+}
+
+// NewStore creates empty in-memory store. Records may be provided to start with existing history.
+func NewStore(records ...migrate.VersionRecord) *Store {
+	return &Store{records: slices.Clone(records)}
+}
+
+// Current implements migrate.VersionStore.
+func (s *Store) Current(ctx context.Context) (migrate.VersionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if s.records[i].Event != migrate.HistoryFailed {
+			return s.records[i], nil
+		}
+	}
+	return migrate.VersionRecord{}, nil
+}
+
+// Record implements migrate.VersionStore.
+func (s *Store) Record(ctx context.Context, rec migrate.VersionRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, rec)
+	return nil
+}
+
+// History implements migrate.VersionStore.
+func (s *Store) History(ctx context.Context, filter migrate.HistoryFilter) ([]migrate.VersionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ret []migrate.VersionRecord
+	for _, rec := range s.records {
+		if matches(rec, filter) {
+			ret = append(ret, rec)
+		}
+	}
+	if filter.Limit > 0 && len(ret) > filter.Limit {
+		ret = ret[len(ret)-filter.Limit:]
+	}
+	return ret, nil
+}
+
+func matches(rec migrate.VersionRecord, filter migrate.HistoryFilter) bool {
+	switch {
+	case filter.Version != 0 && rec.MigrationVersion != filter.Version:
+		return false
+	case filter.Direction != "" && rec.Direction != filter.Direction:
+		return false
+	case len(filter.Events) > 0 && !slices.Contains(filter.Events, rec.Event):
+		return false
+	case !filter.Since.IsZero() && rec.Timestamp.Before(filter.Since):
+		return false
+	case !filter.Until.IsZero() && !rec.Timestamp.Before(filter.Until):
+		return false
+	}
+	return true
+}
+
+// Records returns all history records in order of writing.
+func (s *Store) Records() []migrate.VersionRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.records)
+}
+
+// Lock implements migrate.VersionStore.
+func (s *Store) Lock(ctx context.Context, owner string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.lockOwner != "" && s.lockOwner != owner && now.Before(s.lockExpiration) {
+		return fmt.Errorf("%w: held by %q until %s", migrate.ErrLocked, s.lockOwner, s.lockExpiration.Format(time.RFC3339))
+	}
+	s.lockOwner, s.lockExpiration = owner, now.Add(ttl)
+	return nil
+}
+
+// RefreshLock implements migrate.VersionStore.
+func (s *Store) RefreshLock(ctx context.Context, owner string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lockOwner != owner {
+		return migrate.ErrLockLost
+	}
+	s.lockExpiration = time.Now().Add(ttl)
+	return nil
+}
+
+// Unlock implements migrate.VersionStore.
+func (s *Store) Unlock(ctx context.Context, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lockOwner == owner {
+		s.lockOwner = ""
+	}
+	return nil
+}
+
+// LockOwner returns current lock holder or empty string if lock is not held.
+func (s *Store) LockOwner() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Now().After(s.lockExpiration) {
+		return ""
+	}
+	return s.lockOwner
+}
@@ -3,6 +3,7 @@ package migrate
 import (
 	"context"
 	"sort"
+	"time"
 
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
@@ -20,11 +21,21 @@ type MigrationFunc func(ctx context.Context, db *mongo.Database) error
 // - up: callback which will be called in "up" migration process
 //
 // - down: callback which will be called in "down" migration process for reverting changes
+//
+// - transactional: run callback and version record write in one transaction
+// (requires replica set or sharded cluster, callback may be called several times on transaction retries)
+//
+// - checksum: optional fingerprint of migration content stored in history to detect edited migrations
+//
+// - timeout: optional limit of callback execution time, overrides default one set for Migrate
 type Migration struct {
-	Version     uint64
-	Description string
-	Up          MigrationFunc
-	Down        MigrationFunc
+	Version       uint64
+	Description   string
+	Up            MigrationFunc
+	Down          MigrationFunc
+	Transactional bool
+	Checksum      string
+	Timeout       time.Duration
 }
 
 func migrationSort(migrations []Migration) {
@@ -7,8 +7,11 @@ import (
 	"errors"
 	"net/url"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -628,3 +631,817 @@ func TestMultipleDownMigration(t *testing.T) {
 		return
 	}
 }
+
+func TestLockedMigrations(t *testing.T) {
+	defer cleanup(db)
+	ctx := context.Background()
+	other := NewMigrate(db, Migration{Version: 1, Description: "hello", Up: func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	}})
+	other.SetLockOptions(&LockOptions{Owner: "other"})
+
+	var lockedErr error
+	migrate := NewMigrate(db, Migration{Version: 1, Description: "hello", Up: func(ctx context.Context, db *mongo.Database) error {
+		lockedErr = other.Up(ctx, AllAvailable)
+		return nil
+	}})
+	migrate.SetLockOptions(&LockOptions{Owner: "migrate"})
+
+	if err := migrate.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if !errors.Is(lockedErr, ErrLocked) {
+		t.Errorf("Unexpected error: %v", lockedErr)
+		return
+	}
+	if err := other.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+}
+
+func TestTransactionalMigrationWithErrors(t *testing.T) {
+	defer cleanup(db)
+	expectedErr := errors.New("normal error")
+	ctx := context.Background()
+	migrate := NewMigrate(db,
+		Migration{Version: 1, Description: "hello", Transactional: true, Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(testCollection).InsertOne(ctx, bson.D{{Key: "hello", Value: "world"}})
+			if err != nil {
+				return err
+			}
+			return expectedErr
+		}},
+	)
+	err := migrate.Up(ctx, AllAvailable)
+	switch {
+	case errors.Is(err, ErrTransactionsNotSupported):
+		t.Skip("Transactions are not supported by test deployment")
+	case !errors.Is(err, expectedErr):
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	version, _, err := migrate.Version(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if version != 0 {
+		t.Errorf("Unexpected version: %v", version)
+		return
+	}
+	result := db.Collection(testCollection).FindOne(ctx, bson.D{{Key: "hello", Value: "world"}})
+	if err := result.Decode(&bson.D{}); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+}
+
+func TestMigrationsStatus(t *testing.T) {
+	defer cleanup(db)
+	ctx := context.Background()
+	noop := func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	}
+	other := NewMigrate(db,
+		Migration{Version: 1, Description: "hello", Up: noop},
+		Migration{Version: 2, Description: "world", Up: noop},
+		Migration{Version: 3, Description: "removed", Up: noop},
+	)
+	if err := other.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	migrate := NewMigrate(db,
+		Migration{Version: 1, Description: "hello", Up: noop},
+		Migration{Version: 2, Description: "world", Up: noop},
+		Migration{Version: 4, Description: "pending", Up: noop},
+	)
+	status, err := migrate.Status(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if status.Version != 3 || status.Description != "removed" {
+		t.Errorf("Unexpected version/description: %v %v", status.Version, status.Description)
+		return
+	}
+	if len(status.Migrations) != 3 {
+		t.Errorf("Unexpected migrations: %+v", status.Migrations)
+		return
+	}
+	for _, ms := range status.Migrations[:2] {
+		if !ms.Applied || ms.AppliedAt.IsZero() {
+			t.Errorf("Unexpected not applied migration: %+v", ms)
+		}
+	}
+	if pending := status.Pending(); len(pending) != 1 || pending[0].Version != 4 {
+		t.Errorf("Unexpected pending migrations: %+v", pending)
+	}
+	if len(status.Unknown) != 1 || status.Unknown[0].Version != 3 || !status.Unknown[0].Applied {
+		t.Errorf("Unexpected unknown migrations: %+v", status.Unknown)
+	}
+}
+
+func TestOutOfOrderUpMigrations(t *testing.T) {
+	defer cleanup(db)
+	var applied []uint64
+	ctx := context.Background()
+	migration := func(version uint64) Migration {
+		return Migration{Version: version, Up: func(ctx context.Context, db *mongo.Database) error {
+			applied = append(applied, version)
+			return nil
+		}}
+	}
+	if err := NewMigrate(db, migration(1), migration(3)).Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	migrate := NewMigrate(db, migration(1), migration(2), migration(3))
+	migrate.SetOutOfOrderPolicy(OutOfOrderFail)
+	var gapsErr *GapsError
+	if err := migrate.Up(ctx, AllAvailable); !errors.As(err, &gapsErr) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	migrate.SetOutOfOrderPolicy(OutOfOrderAllow)
+	if err := migrate.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	version, _, err := migrate.Version(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if version != 3 {
+		t.Errorf("Unexpected version: %v", version)
+		return
+	}
+	if len(applied) != 3 || applied[2] != 2 {
+		t.Errorf("Unexpected applied migrations: %v", applied)
+		return
+	}
+}
+
+func TestToMigrations(t *testing.T) {
+	defer cleanup(db)
+	ctx := context.Background()
+	noop := func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	}
+	migrate := NewMigrate(db,
+		Migration{Version: 1, Description: "hello", Up: noop, Down: noop},
+		Migration{Version: 2, Description: "world", Up: noop, Down: noop},
+		Migration{Version: 3, Description: "again", Up: noop, Down: noop},
+	)
+	for _, target := range []uint64{2, 3, 1, 0} {
+		if err := migrate.To(ctx, target); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		version, _, err := migrate.Version(ctx)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		if version != target {
+			t.Errorf("Unexpected version: %v, expected %v", version, target)
+			return
+		}
+	}
+	if err := migrate.To(ctx, 4); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+}
+
+func TestChangedMigrationChecksum(t *testing.T) {
+	defer cleanup(db)
+	ctx := context.Background()
+	noop := func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	}
+	if err := NewMigrate(db, Migration{Version: 1, Up: noop, Checksum: "before"}).Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	migrate := NewMigrate(db, Migration{Version: 1, Up: noop, Checksum: "after"}, Migration{Version: 2, Up: noop})
+	migrate.SetValidateChecksums(true)
+	status, err := migrate.Status(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if changed := status.Changed(); len(changed) != 1 || changed[0].AppliedChecksum != "before" {
+		t.Errorf("Unexpected changed migrations: %+v", changed)
+		return
+	}
+	var checksumErr *ChecksumMismatchError
+	if err := migrate.Up(ctx, AllAvailable); !errors.As(err, &checksumErr) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+}
+
+func TestPlanMigrations(t *testing.T) {
+	defer cleanup(db)
+	var cnt int
+	ctx := context.Background()
+	count := func(ctx context.Context, db *mongo.Database) error {
+		cnt++
+		return nil
+	}
+	migrate := NewMigrate(db,
+		Migration{Version: 1, Description: "hello", Up: count, Down: count},
+		Migration{Version: 2, Description: "world", Up: count, Down: count},
+	)
+	plan, err := migrate.PlanUp(ctx, AllAvailable)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if plan.FromVersion != 0 || plan.ToVersion != 2 || len(plan.Steps) != 2 {
+		t.Errorf("Unexpected plan: %+v", plan)
+		return
+	}
+	version, _, err := migrate.Version(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if version != 0 || cnt != 0 {
+		t.Errorf("Unexpected version or calls count: %v %v", version, cnt)
+		return
+	}
+}
+
+func TestDeclarativeMigrations(t *testing.T) {
+	defer cleanup(db)
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"1_create.up.json":   {Data: []byte(`{"create": "` + testCollection + `"}`)},
+		"1_create.down.json": {Data: []byte(`{"drop": "` + testCollection + `"}`)},
+		"2_index.up.json":    {Data: []byte(`{"createIndexes": "` + testCollection + `", "indexes": [{"key": {"hello": 1}, "name": "test_idx"}]}`)},
+		"2_index.down.json":  {Data: []byte(`{"dropIndexes": "` + testCollection + `", "index": "test_idx"}`)},
+	}
+	migrations, err := LoadFS(fsys, ".")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	migrate := NewMigrate(db, migrations...)
+	if err := migrate.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	specs, err := db.Collection(testCollection).Indexes().ListSpecifications(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(specs) != 2 {
+		t.Errorf("Unexpected indexes: %+v", specs)
+		return
+	}
+	if err := migrate.Down(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	version, _, err := migrate.Version(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if version != 0 {
+		t.Errorf("Unexpected version: %v", version)
+		return
+	}
+}
+
+type countingHooks struct {
+	NopHooks
+	after []MigrationEvent
+}
+
+func (h *countingHooks) AfterMigration(ctx context.Context, event MigrationEvent) {
+	h.after = append(h.after, event)
+}
+
+func TestMigrationHooks(t *testing.T) {
+	defer cleanup(db)
+	ctx := context.Background()
+	noop := func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	}
+	migrate := NewMigrate(db,
+		Migration{Version: 1, Description: "hello", Up: noop, Down: noop},
+		Migration{Version: 2, Description: "world", Up: noop, Down: noop},
+	)
+	hooks := &countingHooks{}
+	migrate.SetHooks(hooks)
+	if err := migrate.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := migrate.Down(ctx, 1); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(hooks.after) != 3 {
+		t.Errorf("Unexpected events: %+v", hooks.after)
+		return
+	}
+	if last := hooks.after[2]; last.Direction != DirectionDown || last.Migration.Version != 2 || last.Version != 1 {
+		t.Errorf("Unexpected event: %+v", last)
+	}
+}
+
+func TestDirtyMigrations(t *testing.T) {
+	defer cleanup(db)
+	expectedErr := errors.New("normal error")
+	ctx := context.Background()
+	failing := true
+	migrate := NewMigrate(db,
+		Migration{Version: 1, Description: "hello", Up: func(ctx context.Context, db *mongo.Database) error {
+			if failing {
+				return expectedErr
+			}
+			return nil
+		}},
+	)
+	migrate.SetDirtyTracking(true)
+	if err := migrate.Up(ctx, AllAvailable); !errors.Is(err, expectedErr) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	failing = false
+	var dirtyErr *DirtyError
+	if err := migrate.Up(ctx, AllAvailable); !errors.As(err, &dirtyErr) || dirtyErr.Version != 1 {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	status, err := migrate.Status(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if status.Dirty == nil || status.Dirty.Direction != DirectionUp {
+		t.Errorf("Unexpected dirty state: %+v", status.Dirty)
+		return
+	}
+
+	if err := migrate.Repair(ctx); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := migrate.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	version, _, err := migrate.Version(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if version != 1 {
+		t.Errorf("Unexpected version: %v", version)
+		return
+	}
+}
+
+func TestMigrationHistory(t *testing.T) {
+	defer cleanup(db)
+	expectedErr := errors.New("normal error")
+	ctx := context.Background()
+	failing := true
+	migrate := NewMigrate(db,
+		Migration{Version: 1, Description: "hello", Up: func(ctx context.Context, db *mongo.Database) error {
+			return nil
+		}, Down: func(ctx context.Context, db *mongo.Database) error {
+			return nil
+		}},
+		Migration{Version: 2, Description: "world", Up: func(ctx context.Context, db *mongo.Database) error {
+			if failing {
+				return expectedErr
+			}
+			return nil
+		}, Down: func(ctx context.Context, db *mongo.Database) error {
+			return nil
+		}},
+	)
+	migrate.SetOperator("tester")
+	if err := migrate.Up(ctx, AllAvailable); !errors.Is(err, expectedErr) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	version, _, err := migrate.Version(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if version != 1 {
+		t.Errorf("Unexpected version: %v", version)
+		return
+	}
+
+	failing = false
+	if err := migrate.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := migrate.Down(ctx, 1); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	entries, err := migrate.History(ctx, HistoryFilter{})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(entries) != 4 {
+		t.Errorf("Unexpected entries: %+v", entries)
+		return
+	}
+	if e := entries[1]; e.Event != HistoryFailed || e.Direction != DirectionUp || e.MigrationVersion != 2 ||
+		e.Version != 1 || e.Error != expectedErr.Error() || e.Operator != "tester" || e.PID == 0 {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+	if e := entries[3]; e.Event != HistoryMigrated || e.Direction != DirectionDown || e.MigrationVersion != 2 || e.Version != 1 {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+
+	failed, err := migrate.History(ctx, HistoryFilter{Events: []HistoryEvent{HistoryFailed}})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(failed) != 1 || failed[0].MigrationVersion != 2 {
+		t.Errorf("Unexpected entries: %+v", failed)
+		return
+	}
+}
+
+func TestSeparateVersionStore(t *testing.T) {
+	defer cleanup(db)
+	storeDB := db.Client().Database(db.Name() + "_versions")
+	defer storeDB.Drop(context.Background())
+	ctx := context.Background()
+
+	migrate := NewMigrate(db, Migration{Version: 1, Description: "hello", Up: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(testCollection).InsertOne(ctx, bson.D{{Key: "hello", Value: "world"}})
+		return err
+	}})
+	migrate.SetVersionStore(NewCollectionStore(storeDB, "history", ""))
+	migrate.SetLockOptions(&LockOptions{})
+	if err := migrate.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	count, err := storeDB.Collection("history").CountDocuments(ctx, bson.D{})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if count != 1 {
+		t.Errorf("Unexpected records count: %v", count)
+	}
+	if exists, err := NewCollectionStore(db, "", "").isCollectionExist(ctx, defaultMigrationsCollection); err != nil || exists {
+		t.Errorf("Unexpected migrations collection in migrations database: %v %v", exists, err)
+	}
+}
+
+func TestTenantRunner(t *testing.T) {
+	ctx := context.Background()
+	expectedErr := errors.New("normal error")
+	prefix := db.Name() + "_tenant_"
+	tenants := []string{prefix + "a", prefix + "b", prefix + "c"}
+	for _, name := range tenants {
+		if err := db.Client().Database(name).CreateCollection(ctx, testCollection); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		defer db.Client().Database(name).Drop(ctx)
+	}
+
+	runner := NewTenantRunner(db.Client(), DatabasesWithPrefix(prefix),
+		Migration{Version: 1, Up: func(ctx context.Context, db *mongo.Database) error {
+			return nil
+		}},
+		Migration{Version: 2, Up: func(ctx context.Context, db *mongo.Database) error {
+			if strings.HasSuffix(db.Name(), "_b") {
+				return expectedErr
+			}
+			return nil
+		}},
+	)
+	runner.SetConcurrency(2)
+	results, err := runner.Up(ctx, AllAvailable)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(results) != 3 {
+		t.Errorf("Unexpected results: %+v", results)
+		return
+	}
+	for _, result := range results {
+		switch result.Database {
+		case prefix + "b":
+			if !errors.Is(result.Err, expectedErr) || result.FromVersion != 0 || result.ToVersion != 1 {
+				t.Errorf("Unexpected result: %+v", result)
+			}
+		default:
+			if result.Err != nil || result.FromVersion != 0 || result.ToVersion != 2 {
+				t.Errorf("Unexpected result: %+v", result)
+			}
+		}
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	defer cleanup(db)
+	expectedErr := errors.New("normal error")
+	ctx := context.Background()
+
+	var docs []any
+	for i := 1; i <= 25; i++ {
+		docs = append(docs, bson.D{{Key: "_id", Value: i}, {Key: "n", Value: i}})
+	}
+	if _, err := db.Collection(testCollection).InsertMany(ctx, docs); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	type doc struct {
+		ID int `bson:"_id"`
+		N  int `bson:"n"`
+	}
+	var (
+		failing = true
+		calls   int
+	)
+	migrate := NewMigrate(db, Migration{Version: 1, Up: func(ctx context.Context, db *mongo.Database) error {
+		_, err := Backfill(ctx, db.Collection(testCollection), func(ctx context.Context, d doc) (mongo.WriteModel, error) {
+			calls++
+			if failing && d.N == 21 {
+				return nil, expectedErr
+			}
+			return mongo.NewUpdateOneModel().
+				SetFilter(bson.D{{Key: "_id", Value: d.ID}}).
+				SetUpdate(bson.D{{Key: "$set", Value: bson.D{{Key: "double", Value: d.N * 2}}}}), nil
+		}, BackfillOptions{BatchSize: 10})
+		return err
+	}})
+	if err := migrate.Up(ctx, AllAvailable); !errors.Is(err, expectedErr) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	failing, calls = false, 0
+	if err := migrate.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if calls != 5 {
+		t.Errorf("Unexpected transform calls after resume: %v", calls)
+	}
+
+	count, err := db.Collection(testCollection).CountDocuments(ctx, bson.D{{Key: "double", Value: bson.D{{Key: "$exists", Value: true}}}})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if count != 25 {
+		t.Errorf("Unexpected processed documents count: %v", count)
+	}
+	count, err = db.Collection(defaultMigrationsCollection+defaultCheckpointsCollectionSuffix).CountDocuments(ctx, bson.D{})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if count != 0 {
+		t.Errorf("Checkpoint is not removed")
+	}
+}
+
+func TestThrottlerReplicationLag(t *testing.T) {
+	throttler := NewThrottler(db.Client(), ThrottleOptions{MaxReplicationLag: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := throttler.Wait(ctx, 1); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestIndexMigrationRoundTrip(t *testing.T) {
+	defer cleanup(db)
+	ctx := context.Background()
+	coll := db.Collection(testCollection)
+
+	oldIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetName("old").SetUnique(true).
+			SetPartialFilterExpression(bson.D{{Key: "email", Value: bson.D{{Key: "$exists", Value: true}}}}).
+			SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+	}
+	if _, err := coll.Indexes().CreateOne(ctx, oldIndex); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	oldSpec, err := indexSpecification(ctx, coll, "old")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	migrate := NewMigrate(db, IndexMigration(1, "indexes",
+		CreateIndex(testCollection, mongo.IndexModel{
+			Keys:    bson.D{{Key: "expires", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(60),
+		}),
+		DropIndex(testCollection, "old"),
+	))
+	if err := migrate.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if _, err := indexSpecification(ctx, coll, "expires_1"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if _, err := indexSpecification(ctx, coll, "old"); err == nil {
+		t.Errorf("Index is not dropped")
+		return
+	}
+
+	if err := migrate.Down(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if _, err := indexSpecification(ctx, coll, "expires_1"); err == nil {
+		t.Errorf("Index is not dropped")
+		return
+	}
+	spec, err := indexSpecification(ctx, coll, "old")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(spec, oldSpec) {
+		t.Errorf("Unexpected recreated index: %v, expected %v", spec, oldSpec)
+	}
+}
+
+// recordFailingStore fails to write first records of performed migrations.
+type recordFailingStore struct {
+	*memoryStore
+	failures int
+}
+
+func (s *recordFailingStore) Record(ctx context.Context, rec VersionRecord) error {
+	if rec.Event == HistoryMigrated && s.failures > 0 {
+		s.failures--
+		return errors.New("record failed")
+	}
+	return s.memoryStore.Record(ctx, rec)
+}
+
+func TestIndexMigrationRecordFailure(t *testing.T) {
+	defer cleanup(db)
+	ctx := context.Background()
+	coll := db.Collection(testCollection)
+
+	if _, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetName("old"),
+	}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	migrate := NewMigrate(db, IndexMigration(1, "indexes",
+		CreateIndex(testCollection, mongo.IndexModel{Keys: bson.D{{Key: "expires", Value: 1}}}),
+		DropIndex(testCollection, "old"),
+	))
+	migrate.SetVersionStore(&recordFailingStore{memoryStore: &memoryStore{}, failures: 1})
+
+	var migrationErr *MigrationError
+	if err := migrate.Up(ctx, AllAvailable); !errors.As(err, &migrationErr) || migrationErr.Phase != PhaseRecord {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	// indexes are already changed, repeated "up" skips dropped index
+	if err := migrate.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if _, err := indexSpecification(ctx, coll, "old"); !errors.Is(err, errIndexNotFound) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	// specification of dropped index is lost, so only created one is reverted
+	if err := migrate.Down(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if _, err := indexSpecification(ctx, coll, "expires_1"); !errors.Is(err, errIndexNotFound) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestValidatorMigration(t *testing.T) {
+	defer cleanup(db)
+	ctx := context.Background()
+
+	previous := Validator{
+		Validator: JSONSchema(bson.D{{Key: "bsonType", Value: "object"}, {Key: "required", Value: bson.A{"name"}}}),
+		Level:     "moderate",
+		Action:    "warn",
+	}
+	if err := db.RunCommand(ctx, validatorCommand("create", testCollection, previous)).Err(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	before, _, err := currentValidator(ctx, db, testCollection)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	migrate := NewMigrate(db, ValidatorMigration(1, "validator", testCollection, Validator{
+		Validator: JSONSchema(bson.D{{Key: "bsonType", Value: "object"}, {Key: "required", Value: bson.A{"email"}}}),
+		Level:     "strict",
+	}))
+	if err := migrate.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	applied, _, err := currentValidator(ctx, db, testCollection)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if applied.Level != "strict" || reflect.DeepEqual(applied.Validator, before.Validator) {
+		t.Errorf("Unexpected validator: %+v", applied)
+		return
+	}
+
+	if err := migrate.Down(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	restored, _, err := currentValidator(ctx, db, testCollection)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(restored, before) {
+		t.Errorf("Unexpected restored validator: %+v, expected %+v", restored, before)
+	}
+}
+
+func TestFirstValidatorMigration(t *testing.T) {
+	defer cleanup(db)
+	ctx := context.Background()
+
+	if err := db.CreateCollection(ctx, testCollection); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	migrate := NewMigrate(db, ValidatorMigration(1, "validator", testCollection, Validator{
+		Validator: JSONSchema(bson.D{{Key: "bsonType", Value: "object"}, {Key: "required", Value: bson.A{"email"}}}),
+		Level:     "moderate",
+		Action:    "warn",
+	}))
+	if err := migrate.Up(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := migrate.Down(ctx, AllAvailable); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	// settings are restored to server defaults
+	restored, _, err := currentValidator(ctx, db, testCollection)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(restored.Validator) != 0 ||
+		(restored.Level != "" && restored.Level != defaultValidationLevel) ||
+		(restored.Action != "" && restored.Action != defaultValidationAction) {
+		t.Errorf("Unexpected restored validator: %+v", restored)
+	}
+}
@@ -1,6 +1,8 @@
 package migrate
 
 import (
+	"context"
+	"errors"
 	"sort"
 	"testing"
 )
@@ -34,3 +36,24 @@ func TestHasVersion(t *testing.T) {
 		t.Errorf("Unexpectedly found version")
 	}
 }
+
+func TestIsTransactional(t *testing.T) {
+	migrate := NewMigrate(nil)
+	if migrate.isTransactional(Migration{Version: 1}) {
+		t.Errorf("Unexpectedly transactional")
+	}
+	if !migrate.isTransactional(Migration{Version: 1, Transactional: true}) {
+		t.Errorf("Unexpectedly not transactional")
+	}
+	migrate.SetTransactional(true)
+	if !migrate.isTransactional(Migration{Version: 1}) {
+		t.Errorf("Unexpectedly not transactional")
+	}
+}
+
+func TestToUnknownVersion(t *testing.T) {
+	migrate := NewMigrate(nil, Migration{Version: 1}, Migration{Version: 3})
+	if err := migrate.To(context.Background(), 2); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
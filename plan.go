@@ -0,0 +1,172 @@
+package migrate
+
+import (
+	"context"
+	"math"
+)
+
+// Direction is a direction of migration.
+type Direction string
+
+const (
+	// DirectionUp means applying migration.
+	DirectionUp Direction = "up"
+	// DirectionDown means reverting migration.
+	DirectionDown Direction = "down"
+)
+
+// PlanStep describes single migration to be performed.
+type PlanStep struct {
+	Direction   Direction
+	Version     uint64
+	Description string
+	// ResultVersion is a database version after performing step.
+	ResultVersion uint64
+}
+
+// Plan describes migrations to be performed by "Up", "Down" or "To" in order of performing.
+type Plan struct {
+	// FromVersion is current database version.
+	FromVersion uint64
+	// ToVersion is database version after performing all steps.
+	ToVersion uint64
+	Steps     []PlanStep
+}
+
+// step is a planned migration with history record to be written after performing it.
+type step struct {
+	direction Direction
+	migration Migration
+	record    VersionRecord
+}
+
+// PlanUp returns migrations which would be applied by "Up" with same argument.
+// Neither migrations are called nor history is written.
+func (m *Migrate) PlanUp(ctx context.Context, n int) (*Plan, error) {
+	state, err := m.state(ctx)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := m.planUp(state, n)
+	if err != nil {
+		return nil, err
+	}
+	return newPlan(state, steps), nil
+}
+
+// PlanDown returns migrations which would be reverted by "Down" with same argument.
+// Neither migrations are called nor history is written.
+func (m *Migrate) PlanDown(ctx context.Context, n int) (*Plan, error) {
+	state, err := m.state(ctx)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := m.planDown(state, n)
+	if err != nil {
+		return nil, err
+	}
+	return newPlan(state, steps), nil
+}
+
+// PlanTo returns migrations which would be performed by "To" with same argument.
+// Neither migrations are called nor history is written.
+func (m *Migrate) PlanTo(ctx context.Context, target uint64) (*Plan, error) {
+	if err := m.checkTarget(target); err != nil {
+		return nil, err
+	}
+	state, err := m.state(ctx)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := m.planTo(state, target)
+	if err != nil {
+		return nil, err
+	}
+	return newPlan(state, steps), nil
+}
+
+func newPlan(state versionState, steps []step) *Plan {
+	plan := &Plan{FromVersion: state.Version, ToVersion: state.Version}
+	for _, s := range steps {
+		plan.Steps = append(plan.Steps, PlanStep{
+			Direction:     s.direction,
+			Version:       s.migration.Version,
+			Description:   s.migration.Description,
+			ResultVersion: s.record.Version,
+		})
+		plan.ToVersion = s.record.Version
+	}
+	return plan
+}
+
+func (m *Migrate) planUp(state versionState, n int) ([]step, error) {
+	if err := m.checkDirty(state); err != nil {
+		return nil, err
+	}
+	if err := m.checkChecksums(state); err != nil {
+		return nil, err
+	}
+	migrations, err := m.upMigrations(state, n, math.MaxUint64)
+	if err != nil {
+		return nil, err
+	}
+	steps, _ := m.upSteps(state, migrations)
+	return steps, nil
+}
+
+func (m *Migrate) planDown(state versionState, n int) ([]step, error) {
+	if err := m.checkDirty(state); err != nil {
+		return nil, err
+	}
+	steps, _ := m.downSteps(state, m.downMigrations(state, n, 0))
+	return steps, nil
+}
+
+func (m *Migrate) planTo(state versionState, target uint64) ([]step, error) {
+	if err := m.checkDirty(state); err != nil {
+		return nil, err
+	}
+	if err := m.checkReversible(state, target); err != nil {
+		return nil, err
+	}
+
+	steps, state := m.downSteps(state, m.downMigrations(state, AllAvailable, target))
+
+	migrations, err := m.upMigrations(state, AllAvailable, target)
+	if err != nil {
+		return nil, err
+	}
+	upSteps, _ := m.upSteps(state, migrations)
+	if len(upSteps) > 0 {
+		// migrations being reverted are not checked as "Down" doesn't check them too
+		if err := m.checkChecksums(state); err != nil {
+			return nil, err
+		}
+	}
+
+	return append(steps, upSteps...), nil
+}
+
+// upSteps builds steps for applying migrations and returns state after performing them.
+func (m *Migrate) upSteps(state versionState, migrations []Migration) ([]step, versionState) {
+	var steps []step
+	for _, migration := range migrations {
+		rec := state.withApplied(m.migrations, migration)
+		steps = append(steps, step{direction: DirectionUp, migration: migration, record: rec})
+		state = stateFromRecord(rec)
+	}
+	return steps, state
+}
+
+// downSteps builds steps for reverting migrations and returns state after performing them.
+func (m *Migrate) downSteps(state versionState, migrations []Migration) ([]step, versionState) {
+	var steps []step
+	for _, migration := range migrations {
+		// without out-of-order tracking database version is a watermark,
+		// so reverting migration also reverts newer ones without "down" function
+		rec := state.withReverted(m.migrations, migration, m.outOfOrder == OutOfOrderIgnore)
+		steps = append(steps, step{direction: DirectionDown, migration: migration, record: rec})
+		state = stateFromRecord(rec)
+	}
+	return steps, state
+}
@@ -0,0 +1,52 @@
+package migrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanSteps(t *testing.T) {
+	migrate := NewMigrate(nil, testMigrations(1, 2, 3, 4)...)
+	state := stateFromRecord(VersionRecord{Version: 2, Applied: []uint64{1, 2}})
+
+	steps, err := migrate.planUp(state, 1)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	plan := newPlan(state, steps)
+	expected := &Plan{FromVersion: 2, ToVersion: 3, Steps: []PlanStep{
+		{Direction: DirectionUp, Version: 3, ResultVersion: 3},
+	}}
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+
+	steps, err = migrate.planDown(state, AllAvailable)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	plan = newPlan(state, steps)
+	expected = &Plan{FromVersion: 2, ToVersion: 0, Steps: []PlanStep{
+		{Direction: DirectionDown, Version: 2, ResultVersion: 1},
+		{Direction: DirectionDown, Version: 1, ResultVersion: 0},
+	}}
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+
+	steps, err = migrate.planTo(state, 4)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	plan = newPlan(state, steps)
+	expected = &Plan{FromVersion: 2, ToVersion: 4, Steps: []PlanStep{
+		{Direction: DirectionUp, Version: 3, ResultVersion: 3},
+		{Direction: DirectionUp, Version: 4, ResultVersion: 4},
+	}}
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+}
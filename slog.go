@@ -0,0 +1,58 @@
+package migrate
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SetSlogLogger sets structured logger to log the migration process.
+// Progress is logged with info level, failures with error level and skipped migrations with debug level.
+// Records contain migration version, description, direction, duration, database name and migrations collection.
+// It may be used together with Logger set by SetLogger.
+func (m *Migrate) SetSlogLogger(logger *slog.Logger) {
+	m.slog = logger
+}
+
+func (m *Migrate) logAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if m.slog == nil || !m.slog.Enabled(ctx, level) {
+		return
+	}
+
+	base := make([]slog.Attr, 0, len(attrs)+2)
+	if m.db != nil {
+		base = append(base, slog.String("database", m.db.Name()))
+	}
+	base = append(base, slog.String("collection", m.migrationsCollection))
+
+	m.slog.LogAttrs(ctx, level, msg, append(base, attrs...)...)
+}
+
+func migrationAttrs(event MigrationEvent) []slog.Attr {
+	return []slog.Attr{
+		slog.Uint64("version", event.Migration.Version),
+		slog.String("description", event.Migration.Description),
+		slog.String("direction", string(event.Direction)),
+	}
+}
+
+// logSkipped logs registered migrations which are not performed in this run.
+func (m *Migrate) logSkipped(ctx context.Context, state versionState, steps []step) {
+	if m.slog == nil || !m.slog.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	planned := make(map[uint64]bool, len(steps))
+	for _, s := range steps {
+		planned[s.migration.Version] = true
+	}
+	for _, migration := range m.migrations {
+		if planned[migration.Version] {
+			continue
+		}
+		m.logAttrs(ctx, slog.LevelDebug, "migration skipped",
+			slog.Uint64("version", migration.Version),
+			slog.String("description", migration.Description),
+			slog.Bool("applied", m.isApplied(state, migration.Version)),
+		)
+	}
+}
@@ -0,0 +1,56 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestSlogFailedMigration(t *testing.T) {
+	var buf bytes.Buffer
+	migrate := NewMigrate(nil,
+		Migration{Version: 1, Description: "applied"},
+		Migration{Version: 2, Description: "broken", Up: func(ctx context.Context, db *mongo.Database) error {
+			return errors.New("normal error")
+		}},
+	)
+	migrate.SetSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	state := stateFromRecord(VersionRecord{Version: 1, Applied: []uint64{1}})
+	steps, err := migrate.planUp(state, AllAvailable)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := migrate.execute(context.Background(), state, steps); err == nil {
+		t.Errorf("Unexpected nil error")
+		return
+	}
+
+	var records []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var rec map[string]any
+		if err := dec.Decode(&rec); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		records = append(records, rec)
+	}
+	if len(records) != 3 {
+		t.Errorf("Unexpected records: %v", records)
+		return
+	}
+	if rec := records[0]; rec["level"] != "DEBUG" || rec["msg"] != "migration skipped" || rec["version"] != float64(1) {
+		t.Errorf("Unexpected record: %v", rec)
+	}
+	if rec := records[2]; rec["level"] != "ERROR" || rec["version"] != float64(2) || rec["direction"] != "up" ||
+		rec["collection"] != "migrations" || rec["error"] != "normal error" {
+		t.Errorf("Unexpected record: %v", rec)
+	}
+}
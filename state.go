@@ -0,0 +1,178 @@
+package migrate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OutOfOrderPolicy defines how "Up" handles not applied migrations with versions lower than current database version.
+// Such migrations usually appear when branches with migrations are merged.
+type OutOfOrderPolicy int
+
+const (
+	// OutOfOrderIgnore skips not applied migrations with versions lower than current one. It is default policy.
+	OutOfOrderIgnore OutOfOrderPolicy = iota
+	// OutOfOrderAllow applies every registered but not applied migration in version order.
+	OutOfOrderAllow
+	// OutOfOrderFail makes "Up" fail with GapsError if there are not applied migrations with versions lower than current one.
+	OutOfOrderFail
+)
+
+// GapsError returned by "Up" with OutOfOrderFail policy if not applied migrations with lower versions found.
+type GapsError struct {
+	// Versions contains versions of not applied migrations lower than current version.
+	Versions []uint64
+}
+
+func (e *GapsError) Error() string {
+	versions := make([]string, len(e.Versions))
+	for i, v := range e.Versions {
+		versions[i] = fmt.Sprint(v)
+	}
+	return fmt.Sprintf("migrate: found not applied migrations with versions lower than current one: %s", strings.Join(versions, ", "))
+}
+
+// versionState represents database state determined from the latest migrations history record.
+type versionState struct {
+	Version     uint64
+	Description string
+	// applied is nil for records written before applied versions tracking or by SetVersion,
+	// every version less or equal than Version considered applied for them
+	applied map[uint64]struct{}
+	// checksums contains checksums of applied migrations if they were known at the moment of applying
+	checksums map[uint64]string
+	// dirty is set if migration was started but not completed
+	dirty *DirtyRecord
+}
+
+func stateFromRecord(rec VersionRecord) versionState {
+	state := versionState{Version: rec.Version, Description: rec.Description, dirty: rec.Dirty}
+	if rec.Applied != nil {
+		state.applied = make(map[uint64]struct{}, len(rec.Applied))
+		for _, v := range rec.Applied {
+			state.applied[v] = struct{}{}
+		}
+	}
+	state.checksums = make(map[uint64]string, len(rec.Checksums))
+	for k, checksum := range rec.Checksums {
+		v, err := strconv.ParseUint(k, 10, 64)
+		if err != nil {
+			continue
+		}
+		state.checksums[v] = checksum
+	}
+	return state
+}
+
+func (s versionState) isApplied(version uint64) bool {
+	if s.applied == nil {
+		return version != 0 && version <= s.Version
+	}
+	_, ok := s.applied[version]
+	return ok
+}
+
+// appliedVersions returns sorted applied versions known from state and registered migrations.
+func (s versionState) appliedVersions(migrations []Migration) []uint64 {
+	if s.applied != nil {
+		ret := make([]uint64, 0, len(s.applied))
+		for v := range s.applied {
+			ret = append(ret, v)
+		}
+		sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+		return ret
+	}
+
+	var ret []uint64
+	for _, migration := range migrations {
+		if s.isApplied(migration.Version) {
+			ret = append(ret, migration.Version)
+		}
+	}
+	// current version may belong to migration which is not registered
+	if s.Version != 0 && !hasVersion(migrations, s.Version) {
+		ret = append(ret, s.Version)
+		sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	}
+	return ret
+}
+
+// toRecord returns history record for state.
+func (s versionState) toRecord() VersionRecord {
+	rec := VersionRecord{Version: s.Version, Description: s.Description}
+	if s.applied != nil {
+		rec.Applied = make([]uint64, 0, len(s.applied))
+		for v := range s.applied {
+			rec.Applied = append(rec.Applied, v)
+		}
+		sort.Slice(rec.Applied, func(i, j int) bool { return rec.Applied[i] < rec.Applied[j] })
+	}
+	for v, checksum := range s.checksums {
+		if rec.Checksums == nil {
+			rec.Checksums = make(map[string]string)
+		}
+		rec.Checksums[strconv.FormatUint(v, 10)] = checksum
+	}
+	return rec
+}
+
+// withApplied returns history record for state after applying migration.
+func (s versionState) withApplied(migrations []Migration, migration Migration) VersionRecord {
+	applied := append(s.appliedVersions(migrations), migration.Version)
+	sort.Slice(applied, func(i, j int) bool { return applied[i] < applied[j] })
+
+	checksums := map[uint64]string{migration.Version: migration.Checksum}
+	for v, checksum := range s.checksums {
+		if v != migration.Version {
+			checksums[v] = checksum
+		}
+	}
+
+	return s.record(migrations, applied, checksums)
+}
+
+// withReverted returns history record for state after reverting migration.
+// If dropNewer is set all versions newer than reverted one are considered not applied too.
+func (s versionState) withReverted(migrations []Migration, migration Migration, dropNewer bool) VersionRecord {
+	var applied []uint64
+	for _, v := range s.appliedVersions(migrations) {
+		if v == migration.Version || (dropNewer && v > migration.Version) {
+			continue
+		}
+		applied = append(applied, v)
+	}
+
+	return s.record(migrations, applied, s.checksums)
+}
+
+func (s versionState) record(migrations []Migration, applied []uint64, checksums map[uint64]string) VersionRecord {
+	// record without applied versions and zero version means that nothing is applied
+	rec := VersionRecord{Applied: applied}
+	if len(applied) == 0 {
+		return rec
+	}
+
+	for _, v := range applied {
+		if checksum := checksums[v]; checksum != "" {
+			if rec.Checksums == nil {
+				rec.Checksums = make(map[string]string)
+			}
+			rec.Checksums[strconv.FormatUint(v, 10)] = checksum
+		}
+	}
+
+	rec.Version = applied[len(applied)-1]
+	switch {
+	case rec.Version == s.Version:
+		rec.Description = s.Description
+	default:
+		for _, migration := range migrations {
+			if migration.Version == rec.Version {
+				rec.Description = migration.Description
+			}
+		}
+	}
+	return rec
+}
@@ -0,0 +1,115 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func testMigrations(versions ...uint64) []Migration {
+	noop := func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	}
+	ret := make([]Migration, len(versions))
+	for i, v := range versions {
+		ret[i] = Migration{Version: v, Up: noop, Down: noop}
+	}
+	return ret
+}
+
+func migrationVersions(migrations []Migration) []uint64 {
+	var ret []uint64
+	for _, migration := range migrations {
+		ret = append(ret, migration.Version)
+	}
+	return ret
+}
+
+func TestUpMigrationsOutOfOrder(t *testing.T) {
+	migrate := NewMigrate(nil, testMigrations(1, 2, 3, 4)...)
+	// version 2 was added after 3 had been applied
+	state := stateFromRecord(VersionRecord{Version: 3, Applied: []uint64{1, 3}})
+
+	migrations, err := migrate.upMigrations(state, AllAvailable, math.MaxUint64)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if versions := migrationVersions(migrations); !reflect.DeepEqual(versions, []uint64{4}) {
+		t.Errorf("Unexpected migrations: %v", versions)
+	}
+
+	migrate.SetOutOfOrderPolicy(OutOfOrderAllow)
+	migrations, err = migrate.upMigrations(state, AllAvailable, math.MaxUint64)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if versions := migrationVersions(migrations); !reflect.DeepEqual(versions, []uint64{2, 4}) {
+		t.Errorf("Unexpected migrations: %v", versions)
+	}
+
+	migrate.SetOutOfOrderPolicy(OutOfOrderFail)
+	_, err = migrate.upMigrations(state, AllAvailable, math.MaxUint64)
+	var gapsErr *GapsError
+	if !errors.As(err, &gapsErr) || !reflect.DeepEqual(gapsErr.Versions, []uint64{2}) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestDownMigrationsOutOfOrder(t *testing.T) {
+	migrate := NewMigrate(nil, testMigrations(1, 2, 3)...)
+	state := stateFromRecord(VersionRecord{Version: 3, Applied: []uint64{1, 3}})
+
+	if versions := migrationVersions(migrate.downMigrations(state, AllAvailable, 0)); !reflect.DeepEqual(versions, []uint64{3, 2, 1}) {
+		t.Errorf("Unexpected migrations: %v", versions)
+	}
+
+	migrate.SetOutOfOrderPolicy(OutOfOrderAllow)
+	if versions := migrationVersions(migrate.downMigrations(state, AllAvailable, 0)); !reflect.DeepEqual(versions, []uint64{3, 1}) {
+		t.Errorf("Unexpected migrations: %v", versions)
+	}
+}
+
+func TestMigrationsToTarget(t *testing.T) {
+	migrate := NewMigrate(nil, testMigrations(1, 2, 3, 4)...)
+	state := stateFromRecord(VersionRecord{Version: 3, Applied: []uint64{1, 2, 3}})
+
+	if versions := migrationVersions(migrate.downMigrations(state, AllAvailable, 1)); !reflect.DeepEqual(versions, []uint64{3, 2}) {
+		t.Errorf("Unexpected migrations: %v", versions)
+	}
+	migrations, err := migrate.upMigrations(state, AllAvailable, 3)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(migrations) != 0 {
+		t.Errorf("Unexpected migrations: %v", migrationVersions(migrations))
+	}
+}
+
+func TestVersionStateRecords(t *testing.T) {
+	migrations := testMigrations(1, 2, 3)
+	state := stateFromRecord(VersionRecord{Version: 3, Applied: []uint64{1, 3}})
+
+	rec := state.withApplied(migrations, migrations[1])
+	if rec.Version != 3 || !reflect.DeepEqual(rec.Applied, []uint64{1, 2, 3}) {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+
+	rec = state.withReverted(migrations, migrations[2], false)
+	if rec.Version != 1 || !reflect.DeepEqual(rec.Applied, []uint64{1}) {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+
+	// legacy record without applied versions
+	state = stateFromRecord(VersionRecord{Version: 2})
+	rec = state.withReverted(migrations, migrations[0], true)
+	if rec.Version != 0 || len(rec.Applied) != 0 {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+}
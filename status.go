@@ -0,0 +1,150 @@
+package migrate
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// MigrationStatus describes state of single migration.
+type MigrationStatus struct {
+	Version     uint64
+	Description string
+	// Applied is true if migration is applied to database.
+	Applied bool
+	// AppliedAt is a time of latest migration application. It is zero if migration is not applied.
+	AppliedAt time.Time
+	// Checksum is a checksum of registered migration.
+	Checksum string
+	// AppliedChecksum is a checksum of migration stored on applying.
+	AppliedChecksum string
+	// Changed is true if migration was changed after applying.
+	Changed bool
+}
+
+// StatusReport describes state of database migrations.
+type StatusReport struct {
+	// Version is current database version.
+	Version uint64
+	// Description is current database version description.
+	Description string
+	// Migrations contains status of each registered migration ordered by version.
+	Migrations []MigrationStatus
+	// Unknown contains versions found in migrations history which are not registered.
+	Unknown []MigrationStatus
+	// Dirty describes started but not completed migration. It is nil if database is not dirty.
+	Dirty *DirtyError
+}
+
+// Changed returns applied migrations which were changed after applying.
+func (s *StatusReport) Changed() []MigrationStatus {
+	var ret []MigrationStatus
+	for _, ms := range s.Migrations {
+		if ms.Changed {
+			ret = append(ret, ms)
+		}
+	}
+	return ret
+}
+
+// Pending returns registered migrations which are not applied yet.
+func (s *StatusReport) Pending() []MigrationStatus {
+	var ret []MigrationStatus
+	for _, ms := range s.Migrations {
+		if !ms.Applied {
+			ret = append(ret, ms)
+		}
+	}
+	return ret
+}
+
+// readHistory returns records changing database version in order of writing.
+func (m *Migrate) readHistory(ctx context.Context) ([]VersionRecord, error) {
+	records, err := m.versionStore().History(ctx, HistoryFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := records[:0]
+	for _, rec := range records {
+		if rec.Event != HistoryFailed {
+			ret = append(ret, rec)
+		}
+	}
+	return ret, nil
+}
+
+// Status returns status of each registered migration and versions from history which are not registered.
+func (m *Migrate) Status(ctx context.Context) (*StatusReport, error) {
+	records, err := m.readHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// record with applied versions list or version bigger than previous one means that migration was applied,
+	// otherwise it was written by "down" migration
+	var (
+		prev      versionState
+		appliedAt = make(map[uint64]VersionRecord)
+	)
+	for _, rec := range records {
+		state := stateFromRecord(rec)
+		for _, v := range state.appliedVersions(m.migrations) {
+			if !prev.isApplied(v) {
+				appliedAt[v] = rec
+			}
+		}
+		prev = state
+	}
+
+	status := &StatusReport{Version: prev.Version, Description: prev.Description}
+	if prev.dirty != nil {
+		status.Dirty = prev.dirty.toError()
+	}
+
+	changed := make(map[uint64]bool)
+	for _, v := range m.checksumMismatches(prev) {
+		changed[v] = true
+	}
+
+	migrationSort(m.migrations)
+	for _, migration := range m.migrations {
+		ms := MigrationStatus{
+			Version:     migration.Version,
+			Description: migration.Description,
+			Applied:     prev.isApplied(migration.Version),
+			Checksum:    migration.Checksum,
+			Changed:     changed[migration.Version],
+		}
+		if ms.Applied {
+			ms.AppliedChecksum = prev.checksums[migration.Version]
+		}
+		if rec, ok := appliedAt[migration.Version]; ok && ms.Applied {
+			ms.AppliedAt = rec.Timestamp
+		}
+		status.Migrations = append(status.Migrations, ms)
+	}
+
+	for version, rec := range appliedAt {
+		if hasVersion(m.migrations, version) {
+			continue
+		}
+		ms := MigrationStatus{
+			Version:         version,
+			Applied:         prev.isApplied(version),
+			AppliedChecksum: prev.checksums[version],
+		}
+		if rec.Version == version {
+			ms.Description = rec.Description
+		}
+		if ms.Applied {
+			ms.AppliedAt = rec.Timestamp
+		}
+		status.Unknown = append(status.Unknown, ms)
+	}
+	sort.Slice(status.Unknown, func(i, j int) bool {
+		return status.Unknown[i].Version < status.Unknown[j].Version
+	})
+
+	return status, nil
+}
@@ -0,0 +1,82 @@
+package migrate
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// VersionStore keeps migrations history and lock.
+// Every action (applied or reverted migration, forced version, etc.) appends record to history,
+// current database state is determined from the latest record which is not a failure record.
+// CollectionStore is used by default, custom implementation may be set with "SetVersionStore".
+type VersionStore interface {
+	// Current returns the latest history record except failure ones (see HistoryFailed).
+	// Zero record is returned if history is empty.
+	Current(ctx context.Context) (VersionRecord, error)
+	// Record appends record to history. Record describes database state after applied or reverted migration
+	// or other action mentioned by record event.
+	Record(ctx context.Context, rec VersionRecord) error
+	// History returns records matching filter in order of writing.
+	History(ctx context.Context, filter HistoryFilter) ([]VersionRecord, error)
+
+	// Lock acquires lock for owner for ttl or prolongs it if owner already holds it.
+	// Error wrapping ErrLocked is returned if lock is held by another owner.
+	Lock(ctx context.Context, owner string, ttl time.Duration) error
+	// RefreshLock prolongs lock held by owner for ttl. ErrLockLost is returned if owner doesn't hold lock anymore.
+	RefreshLock(ctx context.Context, owner string, ttl time.Duration) error
+	// Unlock releases lock held by owner.
+	Unlock(ctx context.Context, owner string) error
+}
+
+// VersionRecord is a record of migrations history describing database state after performed action.
+type VersionRecord struct {
+	// Version is a database version.
+	Version     uint64    `bson:"version"`
+	Description string    `bson:"description,omitempty"`
+	Timestamp   time.Time `bson:"timestamp"`
+	// Applied contains all applied versions. Every version less or equal than Version is considered applied if it is empty.
+	Applied []uint64 `bson:"applied,omitempty"`
+	// Checksums contains checksums of applied migrations keyed by version.
+	Checksums map[string]string `bson:"checksums,omitempty"`
+	// Dirty is set for marker of started but not completed migration.
+	Dirty *DirtyRecord `bson:"dirty,omitempty"`
+	// Data contains values saved by migration function with SaveMigrationData.
+	Data bson.Raw `bson:"data,omitempty"`
+
+	// Following fields describe action which produced record and process which performed it.
+	Event                HistoryEvent `bson:"event,omitempty"`
+	Direction            Direction    `bson:"direction,omitempty"`
+	MigrationVersion     uint64       `bson:"migrationVersion,omitempty"`
+	MigrationDescription string       `bson:"migrationDescription,omitempty"`
+	DurationMS           int64        `bson:"durationMs,omitempty"`
+	Host                 string       `bson:"host,omitempty"`
+	PID                  int          `bson:"pid,omitempty"`
+	Application          string       `bson:"application,omitempty"`
+	Operator             string       `bson:"operator,omitempty"`
+	LibraryVersion       string       `bson:"libraryVersion,omitempty"`
+	Error                string       `bson:"error,omitempty"`
+}
+
+// SetVersionStore replaces storage of migrations history and lock, e.g. to keep it in another database or cluster.
+// By default, CollectionStore in migrations database is used, collection names set by "SetMigrationsCollection"
+// and "SetLockCollection" are ignored when custom store is set.
+// Note that for transactional migrations record is written in the same transaction only by CollectionStore
+// using the same client as migrations database, otherwise it is written right after commit.
+func (m *Migrate) SetVersionStore(store VersionStore) {
+	m.store = store
+}
+
+func (m *Migrate) versionStore() VersionStore {
+	if m.store != nil {
+		return m.store
+	}
+	return NewCollectionStore(m.db, m.migrationsCollection, m.lockCollectionName())
+}
+
+// recordInTransaction reports whether history record can be written in transaction of migration.
+func (m *Migrate) recordInTransaction() bool {
+	store, ok := m.versionStore().(*CollectionStore)
+	return ok && store.db.Client() == m.db.Client()
+}
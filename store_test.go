@@ -0,0 +1,132 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// memoryStore is a VersionStore keeping history in memory.
+type memoryStore struct {
+	mu        sync.Mutex
+	records   []VersionRecord
+	lockOwner string
+}
+
+func (s *memoryStore) Current(ctx context.Context) (VersionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if s.records[i].Event != HistoryFailed {
+			return s.records[i], nil
+		}
+	}
+	return VersionRecord{}, nil
+}
+
+func (s *memoryStore) Record(ctx context.Context, rec VersionRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func (s *memoryStore) History(ctx context.Context, filter HistoryFilter) ([]VersionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ret []VersionRecord
+	for _, rec := range s.records {
+		if (filter.Version == 0 || rec.MigrationVersion == filter.Version) &&
+			(filter.Direction == "" || rec.Direction == filter.Direction) &&
+			(len(filter.Events) == 0 || slices.Contains(filter.Events, rec.Event)) {
+			ret = append(ret, rec)
+		}
+	}
+	if filter.Limit > 0 && len(ret) > filter.Limit {
+		ret = ret[len(ret)-filter.Limit:]
+	}
+	return ret, nil
+}
+
+func (s *memoryStore) Lock(ctx context.Context, owner string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lockOwner != "" && s.lockOwner != owner {
+		return fmt.Errorf("%w: held by %q", ErrLocked, s.lockOwner)
+	}
+	s.lockOwner = owner
+	return nil
+}
+
+func (s *memoryStore) RefreshLock(ctx context.Context, owner string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lockOwner != owner {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (s *memoryStore) Unlock(ctx context.Context, owner string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lockOwner == owner {
+		s.lockOwner = ""
+	}
+	return nil
+}
+
+func TestVersionStore(t *testing.T) {
+	expectedErr := errors.New("normal error")
+	ctx := context.Background()
+	store := &memoryStore{}
+	migrate := NewMigrate(nil, append(testMigrations(1, 2), Migration{Version: 3, Up: func(ctx context.Context, db *mongo.Database) error {
+		return expectedErr
+	}})...)
+	migrate.SetVersionStore(store)
+	migrate.SetLockOptions(&LockOptions{Owner: "me"})
+
+	if err := migrate.Up(ctx, AllAvailable); !errors.Is(err, expectedErr) {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := migrate.Down(ctx, 1); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	version, _, err := migrate.Version(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if version != 1 {
+		t.Errorf("Unexpected version: %v", version)
+		return
+	}
+	if store.lockOwner != "" {
+		t.Errorf("Lock is not released")
+	}
+
+	entries, err := migrate.History(ctx, HistoryFilter{})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(entries) != 4 || entries[2].Event != HistoryFailed || entries[3].Direction != DirectionDown {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+
+	store.lockOwner = "other"
+	if err := migrate.Up(ctx, AllAvailable); !errors.Is(err, ErrLocked) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
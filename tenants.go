@@ -0,0 +1,226 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// tenantVersionTimeout limits reading of database version after failure when context passed to runner is done.
+const tenantVersionTimeout = 10 * time.Second
+
+// DatabaseSelector selects names of client databases to be migrated by TenantRunner.
+type DatabaseSelector func(ctx context.Context, client *mongo.Client) ([]string, error)
+
+// Databases selects databases with provided names.
+func Databases(names ...string) DatabaseSelector {
+	return func(context.Context, *mongo.Client) ([]string, error) {
+		return names, nil
+	}
+}
+
+// DatabasesFunc selects existing databases for which fn returns true.
+// System databases ("admin", "config" and "local") are never selected.
+func DatabasesFunc(fn func(name string) bool) DatabaseSelector {
+	return func(ctx context.Context, client *mongo.Client) ([]string, error) {
+		names, err := client.ListDatabaseNames(ctx, bson.D{})
+		if err != nil {
+			return nil, err
+		}
+
+		var ret []string
+		for _, name := range names {
+			if !isSystemDatabase(name) && fn(name) {
+				ret = append(ret, name)
+			}
+		}
+		return ret, nil
+	}
+}
+
+// DatabasesWithPrefix selects existing databases with names starting with prefix.
+func DatabasesWithPrefix(prefix string) DatabaseSelector {
+	return DatabasesFunc(func(name string) bool {
+		return strings.HasPrefix(name, prefix)
+	})
+}
+
+// DatabasesMatching selects existing databases with names matching regular expression.
+func DatabasesMatching(re *regexp.Regexp) DatabaseSelector {
+	return DatabasesFunc(re.MatchString)
+}
+
+func isSystemDatabase(name string) bool {
+	switch name {
+	case "admin", "config", "local":
+		return true
+	}
+	return false
+}
+
+// TenantResult describes migration of single database.
+type TenantResult struct {
+	Database string
+	// FromVersion is database version before migration.
+	FromVersion uint64
+	// ToVersion is database version after migration, it is a version of last performed migration if Err is set.
+	// If Err is not a MigrationError (e.g. lock was lost or context was cancelled), version is read again.
+	ToVersion uint64
+	Err       error
+}
+
+// TenantResults contains results of TenantRunner in order of databases selection.
+type TenantResults []TenantResult
+
+// Failed returns results of databases which were not migrated successfully.
+func (r TenantResults) Failed() TenantResults {
+	var ret TenantResults
+	for _, result := range r {
+		if result.Err != nil {
+			ret = append(ret, result)
+		}
+	}
+	return ret
+}
+
+// Err returns errors of all failed databases joined or nil if all databases were migrated successfully.
+func (r TenantResults) Err() error {
+	var errs []error
+	for _, result := range r.Failed() {
+		errs = append(errs, fmt.Errorf("database %q: %w", result.Database, result.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// TenantRunner applies the same migrations to many databases of one client (e.g. database per customer).
+// Failure of one database doesn't stop migration of others.
+type TenantRunner struct {
+	client      *mongo.Client
+	selector    DatabaseSelector
+	migrations  []Migration
+	concurrency int
+	configure   func(database string, m *Migrate)
+}
+
+// NewTenantRunner creates runner of migrations for databases selected from client.
+// If no migrations provided registered ones are used.
+// By default, databases are migrated one by one.
+func NewTenantRunner(client *mongo.Client, selector DatabaseSelector, migrations ...Migration) *TenantRunner {
+	if len(migrations) == 0 {
+		migrations = RegisteredMigrations()
+	}
+	return &TenantRunner{
+		client:      client,
+		selector:    selector,
+		migrations:  migrations,
+		concurrency: 1,
+	}
+}
+
+// SetConcurrency sets maximum number of databases migrated at the same time.
+func (r *TenantRunner) SetConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	r.concurrency = n
+}
+
+// SetConfigure sets function called for Migrate of each database before migration,
+// it can be used to set logger, hooks, locking, etc.
+// Function is called concurrently for different databases if concurrency is greater than 1.
+func (r *TenantRunner) SetConfigure(fn func(database string, m *Migrate)) {
+	r.configure = fn
+}
+
+// Up performs "up" migrations for each selected database. Detailed description available in Migrate.Up().
+// Returned error is only an error of databases selection, errors of particular databases are reported in results.
+func (r *TenantRunner) Up(ctx context.Context, n int) (TenantResults, error) {
+	return r.run(ctx, func(ctx context.Context, m *Migrate) error {
+		return m.Up(ctx, n)
+	})
+}
+
+// Down performs "down" migrations for each selected database. Detailed description available in Migrate.Down().
+// Returned error is only an error of databases selection, errors of particular databases are reported in results.
+func (r *TenantRunner) Down(ctx context.Context, n int) (TenantResults, error) {
+	return r.run(ctx, func(ctx context.Context, m *Migrate) error {
+		return m.Down(ctx, n)
+	})
+}
+
+// To migrates each selected database to provided version. Detailed description available in Migrate.To().
+// Returned error is only an error of databases selection, errors of particular databases are reported in results.
+func (r *TenantRunner) To(ctx context.Context, target uint64) (TenantResults, error) {
+	return r.run(ctx, func(ctx context.Context, m *Migrate) error {
+		return m.To(ctx, target)
+	})
+}
+
+func (r *TenantRunner) run(ctx context.Context, fn func(ctx context.Context, m *Migrate) error) (TenantResults, error) {
+	names, err := r.selector(ctx, r.client)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: select databases: %w", err)
+	}
+
+	results := make(TenantResults, len(names))
+	sem := make(chan struct{}, r.concurrency)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		results[i].Database = name
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *TenantResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			r.migrate(ctx, result, fn)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+func (r *TenantRunner) migrate(ctx context.Context, result *TenantResult, fn func(ctx context.Context, m *Migrate) error) {
+	m := NewMigrate(r.client.Database(result.Database), r.migrations...)
+	if r.configure != nil {
+		r.configure(result.Database, m)
+	}
+
+	if result.FromVersion, _, result.Err = m.Version(ctx); result.Err != nil {
+		return
+	}
+	result.Err = fn(ctx, m)
+
+	var migrationErr *MigrationError
+	switch {
+	case errors.As(result.Err, &migrationErr):
+		result.ToVersion = migrationErr.LastVersion
+	case result.Err != nil:
+		// some migrations may be already performed, context may be done at this moment
+		versionCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tenantVersionTimeout)
+		defer cancel()
+
+		var err error
+		if result.ToVersion, _, err = m.Version(versionCtx); err != nil {
+			result.ToVersion = result.FromVersion
+			result.Err = errors.Join(result.Err, fmt.Errorf("migrate: read version after failure: %w", err))
+		}
+	default:
+		result.ToVersion, _, result.Err = m.Version(ctx)
+	}
+}
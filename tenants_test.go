@@ -0,0 +1,94 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestDatabasesSelector(t *testing.T) {
+	names, err := Databases("a", "b")(context.Background(), nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("Unexpected names: %v", names)
+	}
+}
+
+func TestTenantResults(t *testing.T) {
+	expectedErr := errors.New("normal error")
+	results := TenantResults{
+		{Database: "a", FromVersion: 1, ToVersion: 2},
+		{Database: "b", FromVersion: 1, ToVersion: 1, Err: expectedErr},
+	}
+	if failed := results.Failed(); len(failed) != 1 || failed[0].Database != "b" {
+		t.Errorf("Unexpected failed results: %+v", failed)
+	}
+	if err := results.Err(); !errors.Is(err, expectedErr) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := results[:1].Err(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestTenantRunnerConcurrency(t *testing.T) {
+	runner := NewTenantRunner(nil, Databases(), testMigrations(1)...)
+	if runner.concurrency != 1 {
+		t.Errorf("Unexpected concurrency: %v", runner.concurrency)
+	}
+	runner.SetConcurrency(0)
+	if runner.concurrency != 1 {
+		t.Errorf("Unexpected concurrency: %v", runner.concurrency)
+	}
+
+	results, err := runner.Up(context.Background(), AllAvailable)
+	if err != nil || len(results) != 0 {
+		t.Errorf("Unexpected results: %v %v", results, err)
+	}
+}
+
+// failingHooks fails before migration with provided version.
+type failingHooks struct {
+	NopHooks
+	version uint64
+	err     error
+}
+
+func (h failingHooks) BeforeMigration(ctx context.Context, event MigrationEvent) error {
+	if event.Migration.Version == h.version {
+		return h.err
+	}
+	return nil
+}
+
+func TestTenantRunnerVersionAfterFailure(t *testing.T) {
+	expectedErr := errors.New("normal error")
+	// client doesn't connect to server until it is used, history is kept in memory
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	defer client.Disconnect(context.Background())
+
+	runner := NewTenantRunner(client, Databases("a"), testMigrations(1, 2)...)
+	runner.SetConfigure(func(database string, m *Migrate) {
+		m.SetVersionStore(&memoryStore{})
+		m.SetHooks(failingHooks{version: 2, err: expectedErr})
+	})
+
+	results, err := runner.Up(context.Background(), AllAvailable)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(results) != 1 || !errors.Is(results[0].Err, expectedErr) || results[0].FromVersion != 0 || results[0].ToVersion != 1 {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
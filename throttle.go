@@ -0,0 +1,149 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const defaultLagCheckInterval = time.Second
+
+// server error codes returned by "replSetGetStatus" for deployments which are not replica sets
+const (
+	codeCommandNotFound      = 59
+	codeNoReplicationEnabled = 76
+)
+
+// ThrottleOptions limits speed of batch processing to reduce load of production deployment.
+// Zero value means no limits.
+type ThrottleOptions struct {
+	// DocsPerSecond limits average rate of processed documents.
+	DocsPerSecond float64
+	// BatchPause is a pause after each batch.
+	BatchPause time.Duration
+	// MaxReplicationLag enables replication lag guard: processing is paused while any secondary
+	// lags behind primary more than this value according to "replSetGetStatus" command.
+	// Guard is skipped for deployments which are not replica sets.
+	MaxReplicationLag time.Duration
+	// LagCheckInterval is an interval of replication lag checks while processing is paused. Default is 1 second.
+	LagCheckInterval time.Duration
+}
+
+// Throttler pauses batch processing according to ThrottleOptions.
+// It is used by Backfill and may be used in custom batch-oriented migrations.
+type Throttler struct {
+	client    *mongo.Client
+	opts      ThrottleOptions
+	start     time.Time
+	processed int64
+}
+
+// NewThrottler creates throttler. Client is used to check replication lag.
+func NewThrottler(client *mongo.Client, opts ThrottleOptions) *Throttler {
+	if opts.LagCheckInterval <= 0 {
+		opts.LagCheckInterval = defaultLagCheckInterval
+	}
+	return &Throttler{client: client, opts: opts, start: time.Now()}
+}
+
+// Wait should be called after each processed batch with number of documents in it.
+// It blocks until next batch can be processed or context is done.
+func (t *Throttler) Wait(ctx context.Context, docs int) error {
+	t.processed += int64(docs)
+
+	delay := t.opts.BatchPause
+	if rateDelay := t.rateDelay(time.Now()); rateDelay > delay {
+		delay = rateDelay
+	}
+	if err := sleepContext(ctx, delay); err != nil {
+		return err
+	}
+
+	if t.opts.MaxReplicationLag <= 0 {
+		return nil
+	}
+	for {
+		lag, err := t.replicationLag(ctx)
+		if err != nil {
+			return err
+		}
+		if lag <= t.opts.MaxReplicationLag {
+			return nil
+		}
+		if err := sleepContext(ctx, t.opts.LagCheckInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// rateDelay returns time to wait to keep documents rate not greater than limit.
+func (t *Throttler) rateDelay(now time.Time) time.Duration {
+	if t.opts.DocsPerSecond <= 0 {
+		return 0
+	}
+	expected := time.Duration(float64(t.processed) / t.opts.DocsPerSecond * float64(time.Second))
+	return t.start.Add(expected).Sub(now)
+}
+
+type replSetStatus struct {
+	Members []replSetMember `bson:"members"`
+}
+
+type replSetMember struct {
+	StateStr   string    `bson:"stateStr"`
+	OptimeDate time.Time `bson:"optimeDate"`
+}
+
+// lag returns maximum lag of secondaries behind primary.
+func (s replSetStatus) lag() time.Duration {
+	var primary time.Time
+	for _, member := range s.Members {
+		if member.StateStr == "PRIMARY" {
+			primary = member.OptimeDate
+		}
+	}
+	if primary.IsZero() {
+		return 0
+	}
+
+	var lag time.Duration
+	for _, member := range s.Members {
+		if member.StateStr == "SECONDARY" && primary.Sub(member.OptimeDate) > lag {
+			lag = primary.Sub(member.OptimeDate)
+		}
+	}
+	return lag
+}
+
+func (t *Throttler) replicationLag(ctx context.Context) (time.Duration, error) {
+	var status replSetStatus
+	err := t.client.Database("admin").RunCommand(ctx, bson.D{{Key: "replSetGetStatus", Value: 1}}).Decode(&status)
+
+	var cmdErr mongo.CommandError
+	switch {
+	case errors.As(err, &cmdErr) && (cmdErr.Code == codeNoReplicationEnabled || cmdErr.Code == codeCommandNotFound):
+		return 0, nil
+	case err != nil:
+		return 0, err
+	}
+	return status.lag(), nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,54 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestThrottlerRateDelay(t *testing.T) {
+	throttler := NewThrottler(nil, ThrottleOptions{DocsPerSecond: 100})
+	throttler.processed = 200
+	if delay := throttler.rateDelay(throttler.start.Add(time.Second)); delay != time.Second {
+		t.Errorf("Unexpected delay: %v", delay)
+	}
+	if delay := throttler.rateDelay(throttler.start.Add(3 * time.Second)); delay > 0 {
+		t.Errorf("Unexpected delay: %v", delay)
+	}
+
+	throttler = NewThrottler(nil, ThrottleOptions{})
+	throttler.processed = 200
+	if delay := throttler.rateDelay(throttler.start); delay != 0 {
+		t.Errorf("Unexpected delay: %v", delay)
+	}
+}
+
+func TestReplicationLag(t *testing.T) {
+	now := time.Now()
+	status := replSetStatus{Members: []replSetMember{
+		{StateStr: "SECONDARY", OptimeDate: now.Add(-2 * time.Second)},
+		{StateStr: "PRIMARY", OptimeDate: now},
+		{StateStr: "SECONDARY", OptimeDate: now.Add(-5 * time.Second)},
+		{StateStr: "ARBITER"},
+	}}
+	if lag := status.lag(); lag != 5*time.Second {
+		t.Errorf("Unexpected lag: %v", lag)
+	}
+	if lag := (replSetStatus{}).lag(); lag != 0 {
+		t.Errorf("Unexpected lag: %v", lag)
+	}
+}
+
+func TestThrottlerWaitCancel(t *testing.T) {
+	throttler := NewThrottler(nil, ThrottleOptions{BatchPause: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	if err := throttler.Wait(ctx, 10); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if throttler.processed != 10 {
+		t.Errorf("Unexpected processed count: %v", throttler.processed)
+	}
+}
@@ -0,0 +1,42 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrMigrationTimeout returned (wrapped into MigrationError) when migration function is aborted by its own timeout.
+// Cancellation or deadline of context passed to "Up", "Down" or "To" is reported as context error instead.
+var ErrMigrationTimeout = errors.New("migrate: migration timed out")
+
+// SetMigrationTimeout sets default timeout for each migration function.
+// Migration.Timeout overrides it. Zero value means no timeout (default).
+func (m *Migrate) SetMigrationTimeout(timeout time.Duration) {
+	m.migrationTimeout = timeout
+}
+
+func (m *Migrate) migrationTimeoutFor(migration Migration) time.Duration {
+	if migration.Timeout > 0 {
+		return migration.Timeout
+	}
+	return m.migrationTimeout
+}
+
+// callWithTimeout calls migration function with context limited by migration timeout.
+func (m *Migrate) callWithTimeout(ctx context.Context, migration Migration, fn MigrationFunc) error {
+	timeout := m.migrationTimeoutFor(migration)
+	if timeout <= 0 {
+		return fn(ctx, m.db)
+	}
+
+	fnCtx, cancel := context.WithTimeoutCause(ctx, timeout, ErrMigrationTimeout)
+	defer cancel()
+
+	err := fn(fnCtx, m.db)
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(fnCtx), ErrMigrationTimeout) {
+		return fmt.Errorf("%w after %s: %w", ErrMigrationTimeout, timeout, err)
+	}
+	return err
+}
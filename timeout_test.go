@@ -0,0 +1,38 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func waitDone(ctx context.Context, db *mongo.Database) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestMigrationTimeout(t *testing.T) {
+	migrate := NewMigrate(nil)
+	migrate.SetMigrationTimeout(time.Hour)
+
+	err := migrate.callWithTimeout(context.Background(), Migration{Version: 1, Timeout: time.Millisecond}, waitDone)
+	if !errors.Is(err, ErrMigrationTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestMigrationCallerTimeout(t *testing.T) {
+	migrate := NewMigrate(nil)
+	migrate.SetMigrationTimeout(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	err := migrate.callWithTimeout(ctx, Migration{Version: 1}, waitDone)
+	if errors.Is(err, ErrMigrationTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
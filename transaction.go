@@ -0,0 +1,60 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ErrTransactionsNotSupported returned when transactional migration is requested
+// but deployment is a standalone server which does not support transactions.
+var ErrTransactionsNotSupported = errors.New("migrate: transactions are not supported by standalone server, replica set or sharded cluster required")
+
+type helloResult struct {
+	SetName string `bson:"setName"`
+	Msg     string `bson:"msg"`
+}
+
+// SetTransactional makes all migrations run in transactions by default.
+// Migration function and version record write are committed atomically in this case.
+// Migration.Transactional allows to enable transactions only for particular migrations.
+func (m *Migrate) SetTransactional(transactional bool) {
+	m.transactional = transactional
+}
+
+func (m *Migrate) isTransactional(migration Migration) bool {
+	return m.transactional || migration.Transactional
+}
+
+func (m *Migrate) checkTransactionsSupport(ctx context.Context) error {
+	var res helloResult
+	if err := m.db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&res); err != nil {
+		return err
+	}
+
+	// replica set members report set name, mongos reports "isdbgrid"
+	if res.SetName == "" && res.Msg != "isdbgrid" {
+		return ErrTransactionsNotSupported
+	}
+	return nil
+}
+
+// withTransaction runs fn in transaction. Context passed to fn carries session and must be used for all operations.
+// Note that fn may be called several times if transaction is retried.
+func (m *Migrate) withTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := m.checkTransactionsSupport(ctx); err != nil {
+		return err
+	}
+
+	session, err := m.db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx context.Context) (any, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
@@ -8,9 +8,14 @@ import (
 )
 
 func extractVersionDescription(name string) (uint64, string, error) {
+	return extractVersionDescriptionExt(name, ".go")
+}
+
+// extractVersionDescriptionExt extracts version and description from file name like "<version>_<description><ext>".
+func extractVersionDescriptionExt(name, ext string) (uint64, string, error) {
 	base := filepath.Base(name)
 
-	if ext := filepath.Ext(base); ext != ".go" {
+	if !strings.HasSuffix(base, ext) {
 		return 0, "", fmt.Errorf("can not extract version from %q", base)
 	}
 
@@ -24,7 +29,7 @@ func extractVersionDescription(name string) (uint64, string, error) {
 		return 0, "", err
 	}
 
-	description := base[idx+1 : len(base)-len(".go")]
+	description := base[idx+1 : len(base)-len(ext)]
 
 	return version, description, nil
 }
@@ -0,0 +1,173 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// validatorDataKey is a key of migration data with previous validator.
+const validatorDataKey = "validator"
+
+// server defaults of validation settings
+const (
+	defaultValidationLevel  = "strict"
+	defaultValidationAction = "error"
+)
+
+// Validator describes document validation settings of collection.
+type Validator struct {
+	// Validator is a validation document, e.g. made by JSONSchema. Nil value keeps current validator,
+	// empty document disables validation.
+	Validator bson.D `bson:"validator"`
+	// Level is a validation level ("off", "strict" or "moderate"). Empty value keeps current level.
+	Level string `bson:"validationLevel,omitempty"`
+	// Action is a validation action ("error" or "warn"). Empty value keeps current action.
+	Action string `bson:"validationAction,omitempty"`
+}
+
+// JSONSchema returns validation document with "$jsonSchema" operator.
+func JSONSchema(schema any) bson.D {
+	return bson.D{{Key: "$jsonSchema", Value: schema}}
+}
+
+// validatorRecord describes performed validator change in migration data.
+type validatorRecord struct {
+	Collection string    `bson:"collection"`
+	Previous   Validator `bson:"previous"`
+	Applied    Validator `bson:"applied"`
+	// Created is set if collection didn't exist and was created by migration.
+	Created bool `bson:"created,omitempty"`
+}
+
+// ValidatorChange returns "up" function replacing validation settings of collection and "down" function restoring previous ones.
+// Previous settings are read from "listCollections" output when "up" runs and stored in migrations history,
+// so functions must be called by Migrate (directly or using migratetest.VerifyRoundTrip).
+// Settings which were not set before are restored to server defaults (empty validator, "strict" level and "error" action),
+// server reports them in collection options afterwards.
+// If collection doesn't exist, it is created by "up" and dropped by "down". Functions can be passed to Register directly:
+//
+//	func init() {
+//		migrate.MustRegister(migrate.ValidatorChange("users", migrate.Validator{
+//			Validator: migrate.JSONSchema(bson.D{
+//				{Key: "bsonType", Value: "object"},
+//				{Key: "required", Value: bson.A{"email"}},
+//			}),
+//			Level: "moderate",
+//		}))
+//	}
+func ValidatorChange(collection string, validator Validator) (up, down MigrationFunc) {
+	up = func(ctx context.Context, db *mongo.Database) error {
+		// previous settings can not be saved outside of migration, so fail before changing them
+		if _, err := currentMigration(ctx); err != nil {
+			return err
+		}
+
+		previous, exists, err := currentValidator(ctx, db, collection)
+		if err != nil {
+			return err
+		}
+
+		command := validatorCommand("collMod", collection, validator)
+		if !exists {
+			command = validatorCommand("create", collection, validator)
+		}
+		if err := db.RunCommand(ctx, command).Err(); err != nil {
+			return fmt.Errorf("migrate: set validator of collection %q: %w", collection, err)
+		}
+
+		return SaveMigrationData(ctx, validatorDataKey, validatorRecord{
+			Collection: collection,
+			Previous:   previous,
+			Applied:    validator,
+			Created:    !exists,
+		})
+	}
+	down = func(ctx context.Context, db *mongo.Database) error {
+		var rec validatorRecord
+		if err := LoadMigrationData(ctx, validatorDataKey, &rec); err != nil {
+			return err
+		}
+
+		return restoreValidator(ctx, db, rec)
+	}
+	return up, down
+}
+
+// ValidatorMigration builds migration replacing validation settings of collection.
+// Detailed description available in ValidatorChange.
+func ValidatorMigration(version uint64, description, collection string, validator Validator) Migration {
+	up, down := ValidatorChange(collection, validator)
+	return Migration{Version: version, Description: description, Up: up, Down: down}
+}
+
+// restoreValidator reverts validator change described by record.
+func restoreValidator(ctx context.Context, db *mongo.Database, rec validatorRecord) error {
+	if rec.Created {
+		if err := db.Collection(rec.Collection).Drop(ctx); err != nil {
+			return fmt.Errorf("migrate: drop collection %q: %w", rec.Collection, err)
+		}
+		return nil
+	}
+
+	// only changed settings are restored, ones which were not set before are restored to server defaults
+	var previous Validator
+	if rec.Applied.Validator != nil {
+		previous.Validator = rec.Previous.Validator
+		if previous.Validator == nil {
+			previous.Validator = bson.D{}
+		}
+	}
+	if rec.Applied.Level != "" {
+		previous.Level = rec.Previous.Level
+		if previous.Level == "" {
+			previous.Level = defaultValidationLevel
+		}
+	}
+	if rec.Applied.Action != "" {
+		previous.Action = rec.Previous.Action
+		if previous.Action == "" {
+			previous.Action = defaultValidationAction
+		}
+	}
+
+	if err := db.RunCommand(ctx, validatorCommand("collMod", rec.Collection, previous)).Err(); err != nil {
+		return fmt.Errorf("migrate: restore validator of collection %q: %w", rec.Collection, err)
+	}
+	return nil
+}
+
+func validatorCommand(name, collection string, validator Validator) bson.D {
+	command := bson.D{{Key: name, Value: collection}}
+	if validator.Validator != nil {
+		command = append(command, bson.E{Key: "validator", Value: validator.Validator})
+	}
+	if validator.Level != "" {
+		command = append(command, bson.E{Key: "validationLevel", Value: validator.Level})
+	}
+	if validator.Action != "" {
+		command = append(command, bson.E{Key: "validationAction", Value: validator.Action})
+	}
+	return command
+}
+
+// currentValidator reads validation settings of collection. False is returned if collection doesn't exist.
+func currentValidator(ctx context.Context, db *mongo.Database, collection string) (Validator, bool, error) {
+	cursor, err := db.ListCollections(ctx, bson.D{{Key: "name", Value: collection}})
+	if err != nil {
+		return Validator{}, false, err
+	}
+
+	var specs []struct {
+		Options Validator `bson:"options"`
+	}
+	if err := cursor.All(ctx, &specs); err != nil {
+		return Validator{}, false, err
+	}
+	if len(specs) == 0 {
+		return Validator{}, false, nil
+	}
+	return specs[0].Options, true, nil
+}
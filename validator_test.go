@@ -0,0 +1,54 @@
+package migrate
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestValidatorCommand(t *testing.T) {
+	schema := JSONSchema(bson.D{{Key: "bsonType", Value: "object"}})
+	command := validatorCommand("collMod", "users", Validator{Validator: schema, Action: "warn"})
+	expected := bson.D{
+		{Key: "collMod", Value: "users"},
+		{Key: "validator", Value: bson.D{{Key: "$jsonSchema", Value: bson.D{{Key: "bsonType", Value: "object"}}}}},
+		{Key: "validationAction", Value: "warn"},
+	}
+	if !reflect.DeepEqual(command, expected) {
+		t.Errorf("Unexpected command: %v", command)
+	}
+
+	// nil validator keeps current one
+	command = validatorCommand("collMod", "users", Validator{Level: "off"})
+	expected = bson.D{
+		{Key: "collMod", Value: "users"},
+		{Key: "validationLevel", Value: "off"},
+	}
+	if !reflect.DeepEqual(command, expected) {
+		t.Errorf("Unexpected command: %v", command)
+	}
+
+	command = validatorCommand("collMod", "users", Validator{Validator: bson.D{}})
+	expected = bson.D{
+		{Key: "collMod", Value: "users"},
+		{Key: "validator", Value: bson.D{}},
+	}
+	if !reflect.DeepEqual(command, expected) {
+		t.Errorf("Unexpected command: %v", command)
+	}
+
+	command = validatorCommand("create", "users", Validator{})
+	if !reflect.DeepEqual(command, bson.D{{Key: "create", Value: "users"}}) {
+		t.Errorf("Unexpected command: %v", command)
+	}
+}
+
+func TestValidatorChangeOutsideMigration(t *testing.T) {
+	up, _ := ValidatorChange("users", Validator{Level: "off"})
+	// database is not touched, so nil one doesn't cause panic
+	if err := up(context.Background(), nil); err == nil {
+		t.Errorf("Expected error")
+	}
+}